@@ -31,4 +31,36 @@ type Accumulator interface {
 	SetPrecision(precision, interval time.Duration)
 
 	AddError(err error)
+
+	// WithTracking returns an Accumulator that tracks the delivery of the
+	// metrics added to it. At most maxTracked metric groups must be
+	// undelivered at any time.
+	WithTracking(maxTracked int) TrackingAccumulator
+}
+
+// TrackingID identifies a group of metrics added to a TrackingAccumulator.
+type TrackingID uint64
+
+// DeliveryInfo reports the outcome of a tracked metric group.
+type DeliveryInfo interface {
+	// ID is the tracking id of the group.
+	ID() TrackingID
+	// Delivered returns true if every metric of the group was written by the
+	// outputs or dropped, false if any of them was lost.
+	Delivered() bool
+}
+
+// TrackingAccumulator is an Accumulator that reports when groups of metrics
+// have been handled by every output, so that service inputs acknowledge the
+// messages they consume only once they cannot be lost anymore.
+type TrackingAccumulator interface {
+	Accumulator
+
+	// AddTrackingMetricGroup adds a group of metrics, and returns the id
+	// reported once every metric of the group has been handled.
+	AddTrackingMetricGroup(group []Metric) TrackingID
+
+	// Delivered returns the channel receiving the outcome of the tracked
+	// groups, with room for maxTracked outcomes.
+	Delivered() <-chan DeliveryInfo
 }
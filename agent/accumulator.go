@@ -1,10 +1,10 @@
 package agent
 
 import (
-	"log"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
@@ -14,6 +14,8 @@ var (
 
 type MetricMaker interface {
 	Name() string
+	LogName() string
+	Log() telegraf.Logger
 	MakeMetric(
 		measurement string,
 		fields map[string]interface{},
@@ -23,6 +25,12 @@ type MetricMaker interface {
 	) telegraf.Metric
 }
 
+// errorRecorder is implemented by the metric makers that keep track of the
+// errors of their plugin.
+type errorRecorder interface {
+	RecordError(err error)
+}
+
 func NewAccumulator(
 	maker MetricMaker,
 	metrics chan telegraf.Metric,
@@ -41,6 +49,10 @@ type accumulator struct {
 	maker MetricMaker
 
 	precision time.Duration
+
+	// defaultTime, when set, is given to the metrics added without a
+	// timestamp instead of the current time.
+	defaultTime time.Time
 }
 
 func (ac *accumulator) AddFields(
@@ -83,8 +95,11 @@ func (ac *accumulator) AddError(err error) {
 		return
 	}
 	NErrors.Incr(1)
+	if r, ok := ac.maker.(errorRecorder); ok {
+		r.RecordError(err)
+	}
 	//TODO suppress/throttle consecutive duplicate errors?
-	log.Printf("E! Error in plugin [%s]: %s", ac.maker.Name(), err)
+	ac.maker.Log().Errorf("Error in plugin: %s", err)
 }
 
 // SetPrecision takes two time.Duration objects. If the first is non-zero,
@@ -108,12 +123,68 @@ func (ac *accumulator) SetPrecision(precision, interval time.Duration) {
 	}
 }
 
+// SetDefaultTime sets the time given to the metrics added without a
+// timestamp, instead of the current time. The zero time restores the current
+// time.
+func (ac *accumulator) SetDefaultTime(t time.Time) {
+	ac.defaultTime = t
+}
+
+func (ac *accumulator) WithTracking(
+	maxTracked int,
+) telegraf.TrackingAccumulator {
+	return &trackingAccumulator{
+		accumulator: ac,
+		delivered:   make(chan telegraf.DeliveryInfo, maxTracked),
+	}
+}
+
 func (ac accumulator) getTime(t []time.Time) time.Time {
 	var timestamp time.Time
-	if len(t) > 0 {
+	switch {
+	case len(t) > 0:
 		timestamp = t[0]
-	} else {
+	case !ac.defaultTime.IsZero():
+		timestamp = ac.defaultTime
+	default:
 		timestamp = time.Now()
 	}
 	return timestamp.Round(ac.precision)
 }
+
+// trackingAccumulator adds the metrics of the tracked groups to the
+// accumulator. The outcome of a group is sent on delivered by the last
+// output handling one of its metrics.
+type trackingAccumulator struct {
+	*accumulator
+	delivered chan telegraf.DeliveryInfo
+}
+
+func (a *trackingAccumulator) AddTrackingMetricGroup(
+	group []telegraf.Metric,
+) telegraf.TrackingID {
+	metrics := make([]telegraf.Metric, 0, len(group))
+	for _, m := range group {
+		made := a.maker.MakeMetric(m.Name(), m.Fields(), m.Tags(), m.Type(),
+			a.getTime([]time.Time{m.Time()}))
+		if made != nil {
+			metrics = append(metrics, made)
+		}
+	}
+
+	metrics, id := metric.WithGroupTracking(metrics, a.onDelivery)
+	for _, m := range metrics {
+		a.metrics <- m
+	}
+	return id
+}
+
+func (a *trackingAccumulator) Delivered() <-chan telegraf.DeliveryInfo {
+	return a.delivered
+}
+
+// onDelivery never blocks as long as no more than maxTracked groups are
+// undelivered.
+func (a *trackingAccumulator) onDelivery(info telegraf.DeliveryInfo) {
+	a.delivered <- info
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -241,6 +242,36 @@ func TestDifferentPrecisions(t *testing.T) {
 		actual)
 }
 
+func TestSetDefaultTime(t *testing.T) {
+	tick := time.Date(2006, time.February, 10, 12, 0, 10, 0, time.UTC)
+	now := time.Date(2006, time.February, 10, 12, 0, 12, 82912748, time.UTC)
+	metrics := make(chan telegraf.Metric, 10)
+	defer close(metrics)
+	a := NewAccumulator(&TestMetricMaker{}, metrics)
+	a.SetPrecision(time.Second, 0)
+
+	a.SetDefaultTime(tick)
+	a.AddFields("acctest",
+		map[string]interface{}{"value": float64(101)},
+		map[string]string{})
+	testm := <-a.metrics
+	assert.Equal(t, tick.UnixNano(), testm.Time().UnixNano())
+
+	// explicit timestamps are kept
+	a.AddFields("acctest",
+		map[string]interface{}{"value": float64(101)},
+		map[string]string{}, now)
+	testm = <-a.metrics
+	assert.Equal(t, now.Round(time.Second).UnixNano(), testm.Time().UnixNano())
+
+	a.SetDefaultTime(time.Time{})
+	a.AddFields("acctest",
+		map[string]interface{}{"value": float64(101)},
+		map[string]string{})
+	testm = <-a.metrics
+	assert.True(t, testm.Time().After(tick))
+}
+
 func TestAddGauge(t *testing.T) {
 	now := time.Now()
 	metrics := make(chan telegraf.Metric, 10)
@@ -309,12 +340,49 @@ func TestAddCounter(t *testing.T) {
 	assert.Equal(t, testm.Type(), telegraf.Counter)
 }
 
+func TestAddTrackingMetricGroup(t *testing.T) {
+	metrics := make(chan telegraf.Metric, 10)
+	defer close(metrics)
+	a := NewAccumulator(&TestMetricMaker{}, metrics).WithTracking(1)
+
+	m1, err := metric.New("acctest", map[string]string{},
+		map[string]interface{}{"value": float64(101)}, time.Unix(0, 0))
+	require.NoError(t, err)
+	m2, err := metric.New("acctest", map[string]string{},
+		map[string]interface{}{"value": float64(102)}, time.Unix(1, 0),
+		telegraf.Counter)
+	require.NoError(t, err)
+	id := a.AddTrackingMetricGroup([]telegraf.Metric{m1, m2})
+
+	first := <-metrics
+	assert.Equal(t, "acctest value=101 0\n", first.String())
+	second := <-metrics
+	assert.Equal(t, telegraf.Counter, second.Type())
+
+	first.Accept()
+	select {
+	case <-a.Delivered():
+		t.Fatal("group delivered before all its metrics")
+	default:
+	}
+	second.Reject()
+	info := <-a.Delivered()
+	assert.Equal(t, id, info.ID())
+	assert.False(t, info.Delivered())
+}
+
 type TestMetricMaker struct {
 }
 
 func (tm *TestMetricMaker) Name() string {
 	return "TestPlugin"
 }
+func (tm *TestMetricMaker) LogName() string {
+	return tm.Name()
+}
+func (tm *TestMetricMaker) Log() telegraf.Logger {
+	return testutil.Logger{Name: tm.Name()}
+}
 func (tm *TestMetricMaker) MakeMetric(
 	measurement string,
 	fields map[string]interface{},
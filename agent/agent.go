@@ -1,11 +1,15 @@
 package agent
 
 import (
+	"context"
 	"fmt"
+	"hash/fnv"
 	"log"
+	"net/http"
 	"os"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -18,67 +22,122 @@ import (
 // Agent runs telegraf and collects data based on the given config
 type Agent struct {
 	Config *config.Config
+
+	// metricC receives the metrics of the inputs and aggregators. It is kept
+	// across reloads, as the service inputs that keep running also keep
+	// sending to it.
+	metricC chan telegraf.Metric
+	// started holds the service inputs that have been started.
+	started map[*models.RunningInput]bool
+
+	// mu guards Config and connected against the health server.
+	mu sync.Mutex
+	// connected holds the time each output connected.
+	connected map[*models.RunningOutput]time.Time
+	// running is 1 while Run is gathering, read by the health server.
+	running      int32
+	healthServer *http.Server
+	healthAddr   string
 }
 
 // NewAgent returns an Agent struct based off the given Config
 func NewAgent(config *config.Config) (*Agent, error) {
 	a := &Agent{
-		Config: config,
+		Config:    config,
+		metricC:   make(chan telegraf.Metric, 100),
+		started:   make(map[*models.RunningInput]bool),
+		connected: make(map[*models.RunningOutput]time.Time),
 	}
 
-	if !a.Config.Agent.OmitHostname {
-		if a.Config.Agent.Hostname == "" {
-			hostname, err := os.Hostname()
-			if err != nil {
-				return nil, err
-			}
+	if err := setHostname(config); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
 
-			a.Config.Agent.Hostname = hostname
+// setHostname sets the host tag of the config, unless omit_hostname is set.
+func setHostname(c *config.Config) error {
+	if c.Agent.OmitHostname {
+		return nil
+	}
+	if c.Agent.Hostname == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return err
 		}
 
-		config.Tags["host"] = a.Config.Agent.Hostname
+		c.Agent.Hostname = hostname
 	}
 
-	return a, nil
+	c.Tags["host"] = c.Agent.Hostname
+	return nil
 }
 
 // Connect connects to all configured outputs
 func (a *Agent) Connect() error {
 	for _, o := range a.Config.Outputs {
-		switch ot := o.Output.(type) {
-		case telegraf.ServiceOutput:
-			if err := ot.Start(); err != nil {
-				log.Printf("E! Service for output %s failed to start, exiting\n%s\n",
-					o.Name, err.Error())
-				return err
-			}
+		if err := connectOutput(o); err != nil {
+			return err
 		}
+		a.setConnected(o)
+	}
+	return nil
+}
 
-		log.Printf("D! Attempting connection to output: %s\n", o.Name)
-		err := o.Output.Connect()
+func connectOutput(o *models.RunningOutput) error {
+	switch ot := o.Output.(type) {
+	case telegraf.ServiceOutput:
+		if err := ot.Start(); err != nil {
+			log.Printf("E! Service for output %s failed to start, exiting\n%s\n",
+				o.LogName(), err.Error())
+			return err
+		}
+	}
+
+	log.Printf("D! Attempting connection to output: %s\n", o.LogName())
+	err := o.Connect()
+	if err != nil {
+		log.Printf("E! Failed to connect to output %s, retrying in 15s, "+
+			"error was '%s' \n", o.LogName(), err)
+		time.Sleep(15 * time.Second)
+		err = o.Connect()
 		if err != nil {
-			log.Printf("E! Failed to connect to output %s, retrying in 15s, "+
-				"error was '%s' \n", o.Name, err)
-			time.Sleep(15 * time.Second)
-			err = o.Output.Connect()
-			if err != nil {
-				return err
-			}
+			return err
 		}
-		log.Printf("D! Successfully connected to output: %s\n", o.Name)
 	}
+	log.Printf("D! Successfully connected to output: %s\n", o.LogName())
 	return nil
 }
 
-// Close closes the connection to all configured outputs
+// Close stops the service inputs and closes the connection to all configured
+// outputs. It must be called once Run has returned for the last time.
 func (a *Agent) Close() error {
+	a.stopHealthServer()
+	for _, input := range a.Config.Inputs {
+		a.stopInput(input)
+	}
+
 	var err error
 	for _, o := range a.Config.Outputs {
-		err = o.Output.Close()
-		switch ot := o.Output.(type) {
-		case telegraf.ServiceOutput:
-			ot.Stop()
-		}
+		err = closeOutput(o)
+	}
+	return err
+}
+
+// stopInput stops the input if it is a running service input.
+func (a *Agent) stopInput(input *models.RunningInput) {
+	if !a.started[input] {
+		return
+	}
+	input.Input.(telegraf.ServiceInput).Stop()
+	delete(a.started, input)
+}
+
+func closeOutput(o *models.RunningOutput) error {
+	err := o.Close()
+	switch ot := o.Output.(type) {
+	case telegraf.ServiceOutput:
+		ot.Stop()
 	}
 	return err
 }
@@ -88,7 +147,7 @@ func panicRecover(input *models.RunningInput) {
 		trace := make([]byte, 2048)
 		runtime.Stack(trace, true)
 		log.Printf("E! FATAL: Input [%s] panicked: %s, Stack:\n%s\n",
-			input.Name(), err, trace)
+			input.LogName(), err, trace)
 		log.Println("E! PLEASE REPORT THIS PANIC ON GITHUB with " +
 			"stack trace, configuration, and OS information: " +
 			"https://github.com/influxdata/telegraf/issues/new")
@@ -107,24 +166,37 @@ func (a *Agent) gatherer(
 
 	GatherTime := selfstat.RegisterTiming("gather",
 		"gather_time_ns",
-		map[string]string{"input": input.Config.Name},
+		input.StatTags(),
 	)
 
 	acc := NewAccumulator(input, metricC)
-	acc.SetPrecision(a.Config.Agent.Precision.Duration,
-		a.Config.Agent.Interval.Duration)
+	acc.SetPrecision(a.precision(input), a.Config.Agent.Interval.Duration)
+
+	jitter := a.Config.Agent.CollectionJitter.Duration
+	if input.Config.CollectionJitter != 0 {
+		jitter = input.Config.CollectionJitter
+	}
+	offset := a.collectionOffset(input, interval)
+
+	timeout := interval
+	if input.Config.GatherTimeout != 0 {
+		timeout = input.Config.GatherTimeout
+	}
 
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
-		internal.RandomSleep(a.Config.Agent.CollectionJitter.Duration, shutdown)
-
-		start := time.Now()
-		gatherWithTimeout(shutdown, input, acc, interval)
-		elapsed := time.Since(start)
+		// the metrics of aligned inputs are stamped with the start of the
+		// collection interval, before the offset and jitter.
+		var defaultTime time.Time
+		if input.Config.AlignTimestamps {
+			defaultTime = time.Now().Truncate(interval)
+		}
+		sleep(offset, shutdown)
+		internal.RandomSleep(jitter, shutdown)
 
-		GatherTime.Incr(elapsed.Nanoseconds())
+		gather(shutdown, input, acc, timeout, defaultTime, GatherTime)
 
 		select {
 		case <-shutdown:
@@ -135,42 +207,111 @@ func (a *Agent) gatherer(
 	}
 }
 
-// gatherWithTimeout gathers from the given input, with the given timeout.
-//   when the given timeout is reached, gatherWithTimeout logs an error message
-//   but continues waiting for it to return. This is to avoid leaving behind
-//   hung processes, and to prevent re-calling the same hung process over and
-//   over.
-func gatherWithTimeout(
+// collectionOffset returns the delay between the start of each collection
+// interval and the gathering of the input: its collection_offset, or when the
+// agent spreads the inputs, an offset derived from the input configuration so
+// that inputs with the same interval are gathered at different times.
+func (a *Agent) collectionOffset(
+	input *models.RunningInput,
+	interval time.Duration,
+) time.Duration {
+	offset := input.Config.CollectionOffset
+	if offset == 0 && a.Config.Agent.CollectionSpread {
+		h := fnv.New64a()
+		fmt.Fprintf(h, "%s/%d", input.LogName(), input.Checksum)
+		offset = time.Duration(h.Sum64() % uint64(interval))
+	}
+	if offset >= interval {
+		log.Printf("W! collection_offset of input %s is longer than its "+
+			"interval (%s), using %s\n", input.LogName(), interval,
+			offset%interval)
+		offset = offset % interval
+	}
+	return offset
+}
+
+// sleep waits for the duration, or until shutdown is closed.
+func sleep(d time.Duration, shutdown chan struct{}) {
+	if d <= 0 {
+		return
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-shutdown:
+	}
+}
+
+// precision returns the precision of the input, or the agent precision if the
+// input has none.
+func (a *Agent) precision(input *models.RunningInput) time.Duration {
+	if input.Config.Precision != 0 {
+		return input.Config.Precision
+	}
+	return a.Config.Agent.Precision.Duration
+}
+
+// gather runs one gather of the input, unless the previous one is still in
+// flight. It waits for the gather to return, for the timeout, or for shutdown;
+// on timeout or shutdown, the context given to ContextInputs is cancelled, and
+// the gather keeps running in the background until it returns. The following
+// collections are skipped in the meantime, so that an input never has two
+// gathers in flight.
+func gather(
 	shutdown chan struct{},
 	input *models.RunningInput,
 	acc *accumulator,
 	timeout time.Duration,
+	defaultTime time.Time,
+	gatherTime selfstat.Stat,
 ) {
-	ticker := time.NewTicker(timeout)
-	defer ticker.Stop()
-	done := make(chan error)
-	go func() {
-		done <- input.Input.Gather(acc)
-	}()
+	if !input.BeginGather() {
+		input.GathersSkipped.Incr(1)
+		input.Log().Warnf("Previous collection has not completed, " +
+			"skipping this collection")
+		return
+	}
+	if !defaultTime.IsZero() {
+		acc.SetDefaultTime(defaultTime)
+	}
 
-	for {
-		select {
-		case err := <-done:
-			if err != nil {
-				acc.AddError(err)
-			}
-			return
-		case <-ticker.C:
-			err := fmt.Errorf("took longer to collect than collection interval (%s)",
-				timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer input.EndGather()
+		start := time.Now()
+		if err := gatherContext(ctx, input, acc); err != nil {
 			acc.AddError(err)
-			continue
-		case <-shutdown:
-			return
 		}
+		gatherTime.Incr(time.Since(start).Nanoseconds())
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		input.GatherTimeouts.Incr(1)
+		acc.AddError(fmt.Errorf("took longer to collect than timeout (%s)",
+			timeout))
+	case <-shutdown:
 	}
 }
 
+// gatherContext gathers from the input, with the context if the input
+// accepts one.
+func gatherContext(
+	ctx context.Context,
+	input *models.RunningInput,
+	acc telegraf.Accumulator,
+) error {
+	if ci, ok := input.Input.(telegraf.ContextInput); ok {
+		return ci.GatherContext(ctx, acc)
+	}
+	return input.Input.Gather(acc)
+}
+
 // Test verifies that we can 'Gather' from all inputs with their configured
 // Config struct
 func (a *Agent) Test() error {
@@ -198,17 +339,16 @@ func (a *Agent) Test() error {
 		}
 
 		acc := NewAccumulator(input, metricC)
-		acc.SetPrecision(a.Config.Agent.Precision.Duration,
-			a.Config.Agent.Interval.Duration)
+		acc.SetPrecision(a.precision(input), a.Config.Agent.Interval.Duration)
 		input.SetTrace(true)
 		input.SetDefaultTags(a.Config.Tags)
 
-		fmt.Printf("* Plugin: %s, Collection 1\n", input.Name())
+		fmt.Printf("* Plugin: %s, Collection 1\n", input.LogName())
 		if input.Config.Interval != 0 {
 			fmt.Printf("* Internal: %s\n", input.Config.Interval)
 		}
 
-		if err := input.Input.Gather(acc); err != nil {
+		if err := gatherContext(context.Background(), input, acc); err != nil {
 			return err
 		}
 
@@ -217,8 +357,8 @@ func (a *Agent) Test() error {
 		switch input.Name() {
 		case "inputs.cpu", "inputs.mongodb", "inputs.procstat":
 			time.Sleep(500 * time.Millisecond)
-			fmt.Printf("* Plugin: %s, Collection 2\n", input.Name())
-			if err := input.Input.Gather(acc); err != nil {
+			fmt.Printf("* Plugin: %s, Collection 2\n", input.LogName())
+			if err := gatherContext(context.Background(), input, acc); err != nil {
 				return err
 			}
 		}
@@ -227,25 +367,134 @@ func (a *Agent) Test() error {
 	return nil
 }
 
-// flush writes a list of metrics to all configured outputs
-func (a *Agent) flush() {
+// drain writes the metrics left in the outputs at shutdown, once their
+// outputFlusher, closing flushed, has returned. Writes that do not complete
+// before ctx is done are abandoned.
+func (a *Agent) drain(
+	ctx context.Context,
+	flushed map[*models.RunningOutput]chan struct{},
+) {
 	var wg sync.WaitGroup
 
-	wg.Add(len(a.Config.Outputs))
-	for _, o := range a.Config.Outputs {
-		go func(output *models.RunningOutput) {
+	wg.Add(len(flushed))
+	for o, done := range flushed {
+		go func(output *models.RunningOutput, done chan struct{}) {
 			defer wg.Done()
-			err := output.Write()
-			if err != nil {
-				log.Printf("E! Error writing to output [%s]: %s\n",
-					output.Name, err.Error())
-			}
-		}(o)
+			drainOutput(ctx, output, done)
+		}(o, done)
 	}
 
 	wg.Wait()
 }
 
+// drainOutput writes the metrics left in a single output, and logs how many
+// could not be written.
+func drainOutput(
+	ctx context.Context,
+	output *models.RunningOutput,
+	flushed chan struct{},
+) {
+	// wait for the ongoing write to finish before the final flush.
+	select {
+	case <-flushed:
+		written := make(chan struct{})
+		go func() {
+			defer close(written)
+			writeOutput(ctx, output)
+		}()
+		select {
+		case <-written:
+		case <-ctx.Done():
+		}
+	case <-ctx.Done():
+	}
+
+	kept, dropped, abandoned := output.PersistBuffer()
+	if kept+dropped == 0 {
+		return
+	}
+	if abandoned > 0 {
+		output.Log().Errorf("Shutdown timeout reached, abandoning a write of %d metrics",
+			abandoned)
+	}
+	if kept > 0 {
+		output.Log().Warnf("Kept %d unwritten metrics in buffer directory %s",
+			kept, output.Config.BufferDirectory)
+	}
+	if dropped > 0 {
+		output.Log().Errorf("Dropped %d unwritten metrics at shutdown", dropped)
+	}
+}
+
+// writeOutput writes the buffered metrics of a single output.
+func writeOutput(ctx context.Context, output *models.RunningOutput) {
+	err := output.WriteContext(ctx)
+	if err != nil {
+		log.Printf("E! Error writing to output [%s]: %s\n",
+			output.LogName(), err.Error())
+	}
+}
+
+// outputFlusher writes to a single output on its flush interval, and whenever
+// the output has a full batch ready. Each output has its own outputFlusher, so
+// a slow output only delays its own writes.
+func (a *Agent) outputFlusher(shutdown chan struct{}, output *models.RunningOutput) {
+	interval := a.Config.Agent.FlushInterval.Duration
+	// overwrite global flush interval if this output has it's own.
+	if output.Config.FlushInterval != 0 {
+		interval = output.Config.FlushInterval
+	}
+	jitter := a.Config.Agent.FlushJitter.Duration
+	if output.Config.FlushJitter != 0 {
+		jitter = output.Config.FlushJitter
+	}
+
+	// writes in flight are cancelled at shutdown, the metrics are written by
+	// the final flush instead.
+	ctx, cancel := shutdownContext(shutdown)
+	defer cancel()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-shutdown:
+			return
+		case <-ticker.C:
+			internal.RandomSleep(jitter, shutdown)
+			writeOutput(ctx, output)
+		case <-output.BatchReady:
+			writeOutput(ctx, output)
+		}
+	}
+}
+
+// shutdownContext returns a context that is cancelled when shutdown is closed.
+func shutdownContext(
+	shutdown chan struct{},
+) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-shutdown:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// shutdownTimeoutContext returns the context bounding the final flush at
+// shutdown, by the shutdown_timeout of the agent if set.
+func (a *Agent) shutdownTimeoutContext() (context.Context, context.CancelFunc) {
+	if a.Config.Agent.ShutdownTimeout.Duration > 0 {
+		return context.WithTimeout(context.Background(),
+			a.Config.Agent.ShutdownTimeout.Duration)
+	}
+	return context.WithCancel(context.Background())
+}
+
 // flusher monitors the metrics input channel and flushes on the minimum interval
 func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric) error {
 	// Inelegant, but this sleep is to allow the Gather threads to run, so that
@@ -255,80 +504,82 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric) er
 	// create an output metric channel and a gorouting that continously passes
 	// each metric onto the output plugins & aggregators.
 	outMetricC := make(chan telegraf.Metric, 100)
+	processors := newPipeline(a.Config.Processors,
+		a.Config.Agent.ProcessorWorkers, a.Config.Agent.ProcessorQueueSize,
+		outMetricC)
+	processors.start()
+
+	router := models.NewRouter(a.Config.Routes, a.Config.Outputs)
+
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		for {
-			select {
-			case <-shutdown:
-				if len(outMetricC) > 0 {
-					// keep going until outMetricC is flushed
-					continue
-				}
-				return
-			case m := <-outMetricC:
-				// if dropOriginal is set to true, then we will only send this
-				// metric to the aggregators, not the outputs.
-				var dropOriginal bool
-				if !m.IsAggregate() {
-					for _, agg := range a.Config.Aggregators {
-						if ok := agg.Add(m.Copy()); ok {
-							dropOriginal = true
-						}
+		// outMetricC is closed once the processors have been drained at
+		// shutdown.
+		for m := range outMetricC {
+			// if dropOriginal is set to true, then we will only send this
+			// metric to the aggregators, not the outputs.
+			var dropOriginal bool
+			if !m.IsAggregate() {
+				for _, agg := range a.Config.Aggregators {
+					if ok := agg.Add(m.Copy()); ok {
+						dropOriginal = true
 					}
 				}
-				if !dropOriginal {
-					for i, o := range a.Config.Outputs {
-						if i == len(a.Config.Outputs)-1 {
-							o.AddMetric(m)
-						} else {
-							o.AddMetric(m.Copy())
-						}
-					}
+			}
+			// metrics going to no output are handled as far as delivery
+			// tracking is concerned.
+			if dropOriginal {
+				m.Drop()
+				continue
+			}
+			outputs := router.Route(m)
+			if len(outputs) == 0 {
+				m.Drop()
+				continue
+			}
+			for i, o := range outputs {
+				if i == len(outputs)-1 {
+					o.AddMetric(m)
+				} else {
+					o.AddMetric(m.Copy())
 				}
 			}
 		}
 	}()
 
-	ticker := time.NewTicker(a.Config.Agent.FlushInterval.Duration)
-	semaphore := make(chan struct{}, 1)
+	flushed := make(map[*models.RunningOutput]chan struct{})
+	for _, o := range a.Config.Outputs {
+		done := make(chan struct{})
+		flushed[o] = done
+		go func(output *models.RunningOutput) {
+			defer close(done)
+			a.outputFlusher(shutdown, output)
+		}(o)
+	}
+
 	for {
 		select {
 		case <-shutdown:
 			log.Println("I! Hang on, flushing any cached metrics before shutdown")
-			// wait for outMetricC to get flushed before flushing outputs
+			ctx, cancel := a.shutdownTimeoutContext()
+			defer cancel()
+			// wait for the processors and outMetricC to get flushed before
+			// flushing outputs
+			processors.stop()
 			wg.Wait()
-			a.flush()
+			a.drain(ctx, flushed)
 			return nil
-		case <-ticker.C:
-			go func() {
-				select {
-				case semaphore <- struct{}{}:
-					internal.RandomSleep(a.Config.Agent.FlushJitter.Duration, shutdown)
-					a.flush()
-					<-semaphore
-				default:
-					// skipping this flush because one is already happening
-					log.Println("W! Skipping a scheduled flush because there is" +
-						" already a flush ongoing.")
-				}
-			}()
 		case metric := <-metricC:
-			// NOTE potential bottleneck here as we put each metric through the
-			// processors serially.
-			mS := []telegraf.Metric{metric}
-			for _, processor := range a.Config.Processors {
-				mS = processor.Apply(mS...)
-			}
-			for _, m := range mS {
-				outMetricC <- m
-			}
+			processors.add(metric)
 		}
 	}
 }
 
-// Run runs the agent daemon, gathering every Interval
+// Run runs the agent daemon, gathering every Interval, until shutdown is
+// closed. Service inputs and outputs are left running when Run returns, so
+// that the agent can be reloaded; Close stops them.
 func (a *Agent) Run(shutdown chan struct{}) error {
 	var wg sync.WaitGroup
 
@@ -338,10 +589,13 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 		a.Config.Agent.Hostname, a.Config.Agent.FlushInterval.Duration)
 
 	// channel shared between all input threads for accumulating metrics
-	metricC := make(chan telegraf.Metric, 100)
+	metricC := a.metricC
 
-	// Start all ServicePlugins
+	// Start the ServicePlugins that are not running yet
 	for _, input := range a.Config.Inputs {
+		if a.started[input] {
+			continue
+		}
 		input.SetDefaultTags(a.Config.Tags)
 		switch p := input.Input.(type) {
 		case telegraf.ServiceInput:
@@ -351,13 +605,19 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 			acc.SetPrecision(time.Nanosecond, 0)
 			if err := p.Start(acc); err != nil {
 				log.Printf("E! Service for input %s failed to start, exiting\n%s\n",
-					input.Name(), err.Error())
+					input.LogName(), err.Error())
 				return err
 			}
-			defer p.Stop()
+			a.started[input] = true
 		}
 	}
 
+	if err := a.startHealthServer(); err != nil {
+		return err
+	}
+	atomic.StoreInt32(&a.running, 1)
+	defer atomic.StoreInt32(&a.running, 0)
+
 	// Round collection to nearest interval by sleeping
 	if a.Config.Agent.RoundInterval {
 		i := int64(a.Config.Agent.Interval.Duration)
@@ -398,6 +658,5 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 	}
 
 	wg.Wait()
-	a.Close()
 	return nil
 }
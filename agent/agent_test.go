@@ -2,8 +2,10 @@ package agent
 
 import (
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
 
 	// needing to load the plugins
 	_ "github.com/influxdata/telegraf/plugins/inputs/all"
@@ -109,3 +111,34 @@ func TestAgent_LoadOutput(t *testing.T) {
 	a, _ = NewAgent(c)
 	assert.Equal(t, 3, len(a.Config.Outputs))
 }
+
+func TestAgent_CollectionOffset(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.OmitHostname = true
+	a, err := NewAgent(c)
+	assert.NoError(t, err)
+
+	mysql := models.NewRunningInput(nil, &models.InputConfig{Name: "mysql"})
+	snmp := models.NewRunningInput(nil, &models.InputConfig{Name: "snmp"})
+	fixed := models.NewRunningInput(nil, &models.InputConfig{
+		Name:             "ping",
+		CollectionOffset: 3 * time.Second,
+	})
+	long := models.NewRunningInput(nil, &models.InputConfig{
+		Name:             "ping",
+		CollectionOffset: 25 * time.Second,
+	})
+
+	interval := 10 * time.Second
+	assert.Equal(t, time.Duration(0), a.collectionOffset(mysql, interval))
+	assert.Equal(t, 3*time.Second, a.collectionOffset(fixed, interval))
+	assert.Equal(t, 5*time.Second, a.collectionOffset(long, interval))
+
+	// spread offsets are within the interval, and do not change
+	c.Agent.CollectionSpread = true
+	offset := a.collectionOffset(mysql, interval)
+	assert.True(t, offset >= 0 && offset < interval)
+	assert.Equal(t, offset, a.collectionOffset(mysql, interval))
+	assert.NotEqual(t, offset, a.collectionOffset(snmp, interval))
+	assert.Equal(t, 3*time.Second, a.collectionOffset(fixed, interval))
+}
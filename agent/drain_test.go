@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingOutput writes until release is closed.
+type blockingOutput struct {
+	release chan struct{}
+	written int
+}
+
+func (o *blockingOutput) SampleConfig() string { return "" }
+func (o *blockingOutput) Description() string  { return "" }
+func (o *blockingOutput) Connect() error       { return nil }
+func (o *blockingOutput) Close() error         { return nil }
+func (o *blockingOutput) Write(metrics []telegraf.Metric) error {
+	<-o.release
+	o.written += len(metrics)
+	return nil
+}
+
+func TestDrain_WritesBufferedMetrics(t *testing.T) {
+	bo := &blockingOutput{release: make(chan struct{})}
+	close(bo.release)
+	output := models.NewRunningOutput("blocking", bo,
+		&models.OutputConfig{Alias: "drain_test"}, 2, 10)
+	for i := 0; i < 3; i++ {
+		output.AddMetric(testutil.TestMetric(i))
+	}
+
+	flushed := make(chan struct{})
+	close(flushed)
+	drainOutput(context.Background(), output, flushed)
+	assert.Equal(t, 3, bo.written)
+	assert.Equal(t, int64(0), output.MetricsDropped.Get())
+}
+
+func TestDrain_AbandonsWriteAfterTimeout(t *testing.T) {
+	bo := &blockingOutput{release: make(chan struct{})}
+	defer close(bo.release)
+	output := models.NewRunningOutput("blocking", bo,
+		&models.OutputConfig{Alias: "drain_timeout_test"}, 2, 10)
+	for i := 0; i < 3; i++ {
+		output.AddMetric(testutil.TestMetric(i))
+	}
+
+	flushed := make(chan struct{})
+	close(flushed)
+	ctx, cancel := context.WithTimeout(context.Background(),
+		50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		drainOutput(ctx, output, flushed)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("drain did not return after the shutdown timeout")
+	}
+
+	// the full batch is being written and the rest of the buffer is
+	// dropped, the abandoned batch is counted as dropped too.
+	assert.Equal(t, 2, output.Writing())
+	assert.Equal(t, int64(3), output.MetricsDropped.Get())
+}
+
+func TestDrain_TimeoutWhileFlushing(t *testing.T) {
+	output := models.NewRunningOutput("blocking",
+		&blockingOutput{release: make(chan struct{})},
+		&models.OutputConfig{Alias: "drain_flushing_test"}, 2, 10)
+	output.AddMetric(testutil.TestMetric(1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	drainOutput(ctx, output, make(chan struct{}))
+	assert.Equal(t, int64(1), output.MetricsDropped.Get())
+}
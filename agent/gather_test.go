@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/selfstat"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingInput gathers until release is closed.
+type blockingInput struct {
+	release chan struct{}
+}
+
+func (i *blockingInput) SampleConfig() string { return "" }
+func (i *blockingInput) Description() string  { return "" }
+func (i *blockingInput) Gather(acc telegraf.Accumulator) error {
+	<-i.release
+	return nil
+}
+
+// contextInput gathers until its context is done.
+type contextInput struct {
+	cancelled chan struct{}
+}
+
+func (i *contextInput) SampleConfig() string                  { return "" }
+func (i *contextInput) Description() string                   { return "" }
+func (i *contextInput) Gather(acc telegraf.Accumulator) error { return nil }
+func (i *contextInput) GatherContext(ctx context.Context, acc telegraf.Accumulator) error {
+	<-ctx.Done()
+	close(i.cancelled)
+	return ctx.Err()
+}
+
+func newGatherAccumulator(input *models.RunningInput) *accumulator {
+	return NewAccumulator(input, make(chan telegraf.Metric, 10))
+}
+
+func TestGather_SkipsWhileInFlight(t *testing.T) {
+	bi := &blockingInput{release: make(chan struct{})}
+	input := models.NewRunningInput(bi,
+		&models.InputConfig{Name: "exec", Alias: "skip_test"})
+	acc := newGatherAccumulator(input)
+	gatherTime := selfstat.Register("gather", "gather_time_ns",
+		map[string]string{"input": "exec", "alias": "skip_test"})
+	shutdown := make(chan struct{})
+
+	// the first gather times out but stays in flight
+	gather(shutdown, input, acc, 10*time.Millisecond, time.Time{}, gatherTime)
+	assert.Equal(t, int64(1), input.GatherTimeouts.Get())
+
+	gather(shutdown, input, acc, 10*time.Millisecond, time.Time{}, gatherTime)
+	assert.Equal(t, int64(1), input.GathersSkipped.Get())
+	assert.Equal(t, int64(1), input.GatherTimeouts.Get())
+
+	// once it returns, the input is gathered again
+	close(bi.release)
+	require.True(t, waitFor(func() bool {
+		if !input.BeginGather() {
+			return false
+		}
+		input.EndGather()
+		return true
+	}))
+	gather(shutdown, input, acc, time.Second, time.Time{}, gatherTime)
+	assert.Equal(t, int64(1), input.GathersSkipped.Get())
+	assert.Equal(t, int64(1), input.GatherTimeouts.Get())
+}
+
+func TestGather_CancelsContextOnTimeout(t *testing.T) {
+	ci := &contextInput{cancelled: make(chan struct{})}
+	input := models.NewRunningInput(ci,
+		&models.InputConfig{Name: "http", Alias: "timeout_test"})
+	acc := newGatherAccumulator(input)
+	gatherTime := selfstat.Register("gather", "gather_time_ns",
+		map[string]string{"input": "http", "alias": "timeout_test"})
+
+	gather(make(chan struct{}), input, acc, 10*time.Millisecond, time.Time{},
+		gatherTime)
+	select {
+	case <-ci.cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("gather context was not cancelled")
+	}
+	assert.Equal(t, int64(1), input.GatherTimeouts.Get())
+	_, err := input.LastGatherError()
+	assert.Error(t, err)
+}
+
+// waitFor polls cond until it is true, for up to a second.
+func waitFor(cond func() bool) bool {
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
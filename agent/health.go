@@ -0,0 +1,180 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/models"
+)
+
+// healthReport is the body of the /health response.
+type healthReport struct {
+	Healthy bool           `json:"healthy"`
+	Outputs []outputHealth `json:"outputs"`
+	Inputs  []inputHealth  `json:"inputs"`
+}
+
+type outputHealth struct {
+	Name        string     `json:"name"`
+	Alias       string     `json:"alias,omitempty"`
+	Healthy     bool       `json:"healthy"`
+	Reasons     []string   `json:"reasons,omitempty"`
+	LastWrite   *time.Time `json:"last_write,omitempty"`
+	BufferSize  int64      `json:"buffer_size"`
+	BufferLimit int64      `json:"buffer_limit"`
+	BufferFill  float64    `json:"buffer_fill"`
+}
+
+type inputHealth struct {
+	Name          string     `json:"name"`
+	Alias         string     `json:"alias,omitempty"`
+	Healthy       bool       `json:"healthy"`
+	Reasons       []string   `json:"reasons,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorTime *time.Time `json:"last_error_time,omitempty"`
+}
+
+// startHealthServer starts the HTTP server reporting the health of the agent
+// on the health_listen address. The server keeps running across reloads,
+// unless the address changes.
+func (a *Agent) startHealthServer() error {
+	addr := a.Config.Agent.HealthListen
+	if a.healthServer != nil {
+		if a.healthAddr == addr {
+			return nil
+		}
+		a.stopHealthServer()
+	}
+	if addr == "" {
+		return nil
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("unable to start health server: %s", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", a.serveHealth)
+	mux.HandleFunc("/ready", a.serveReady)
+	srv := &http.Server{Handler: mux}
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Printf("E! Health server stopped: %s\n", err)
+		}
+	}()
+	log.Printf("I! Serving health on http://%s/health\n", ln.Addr())
+
+	a.healthServer = srv
+	a.healthAddr = addr
+	return nil
+}
+
+func (a *Agent) stopHealthServer() {
+	if a.healthServer == nil {
+		return
+	}
+	a.healthServer.Close()
+	a.healthServer = nil
+	a.healthAddr = ""
+}
+
+// serveHealth reports the health of the outputs and inputs, with the status
+// 503 when any of them is unhealthy.
+func (a *Agent) serveHealth(w http.ResponseWriter, r *http.Request) {
+	report := a.health(time.Now())
+
+	w.Header().Set("Content-Type", "application/json")
+	if !report.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// serveReady reports whether the agent is running, with the status 503 while
+// it is starting or reloading its configuration.
+func (a *Agent) serveReady(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&a.running) == 0 {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ready")
+}
+
+// health checks the outputs and inputs against the thresholds of the agent
+// config, using their selfstat values.
+func (a *Agent) health(now time.Time) *healthReport {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	conf := a.Config.Agent
+	report := &healthReport{
+		Healthy: true,
+		Outputs: []outputHealth{},
+		Inputs:  []inputHealth{},
+	}
+
+	for _, o := range a.Config.Outputs {
+		h := outputHealth{
+			Name:        o.Name,
+			Alias:       o.Config.Alias,
+			BufferSize:  o.BufferSize.Get(),
+			BufferLimit: o.BufferLimit.Get(),
+			BufferFill:  o.BufferFill(),
+		}
+
+		// outputs that never wrote are measured from when they connected.
+		since := a.connected[o]
+		if ns := o.LastWrite.Get(); ns != 0 {
+			t := time.Unix(0, ns)
+			h.LastWrite = &t
+			since = t
+		}
+
+		if max := conf.HealthMaxWriteAge.Duration; max > 0 &&
+			!since.IsZero() && now.Sub(since) > max {
+			h.Reasons = append(h.Reasons, fmt.Sprintf(
+				"no successful write for %s", now.Sub(since)))
+		}
+		if max := conf.HealthMaxBufferFill; max > 0 && h.BufferFill > max {
+			h.Reasons = append(h.Reasons, fmt.Sprintf(
+				"buffer %.0f%% full", h.BufferFill*100))
+		}
+		h.Healthy = len(h.Reasons) == 0
+		report.Healthy = report.Healthy && h.Healthy
+		report.Outputs = append(report.Outputs, h)
+	}
+
+	for _, input := range a.Config.Inputs {
+		h := inputHealth{
+			Name:  input.Name(),
+			Alias: input.Config.Alias,
+		}
+		if t, err := input.LastGatherError(); err != nil {
+			h.LastError = internal.Redact(err.Error())
+			h.LastErrorTime = &t
+			if max := conf.HealthMaxErrorAge.Duration; max > 0 &&
+				now.Sub(t) < max {
+				h.Reasons = append(h.Reasons, fmt.Sprintf(
+					"gather error %s ago", now.Sub(t)))
+			}
+		}
+		h.Healthy = len(h.Reasons) == 0
+		report.Healthy = report.Healthy && h.Healthy
+		report.Inputs = append(report.Inputs, h)
+	}
+	return report
+}
+
+// setConnected records the time the output connected.
+func (a *Agent) setConnected(o *models.RunningOutput) {
+	a.mu.Lock()
+	a.connected[o] = time.Now()
+	a.mu.Unlock()
+}
@@ -0,0 +1,173 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newHealthAgent(t *testing.T) *Agent {
+	c := newReloadConfig()
+	c.Agent.HealthMaxWriteAge.Duration = time.Minute
+	c.Agent.HealthMaxBufferFill = 0.5
+	c.Agent.HealthMaxErrorAge.Duration = time.Minute
+
+	c.Outputs = append(c.Outputs, models.NewRunningOutput("influxdb",
+		&countingOutput{},
+		&models.OutputConfig{Name: "influxdb", Alias: "health_test"}, 10, 100))
+	c.Inputs = append(c.Inputs, models.NewRunningInput(&serviceInput{},
+		&models.InputConfig{Name: "statsd", Alias: "health_test"}))
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	require.NoError(t, a.Connect())
+	return a
+}
+
+func TestHealth(t *testing.T) {
+	a := newHealthAgent(t)
+	ro, input := a.Config.Outputs[0], a.Config.Inputs[0]
+	now := time.Now()
+
+	ro.LastWrite.Set(now.Add(-time.Second).UnixNano())
+	ro.BufferSize.Set(20)
+	report := a.health(now)
+	assert.True(t, report.Healthy)
+	require.Len(t, report.Outputs, 1)
+	assert.Equal(t, 0.2, report.Outputs[0].BufferFill)
+	require.Len(t, report.Inputs, 1)
+	assert.Equal(t, "", report.Inputs[0].LastError)
+
+	// the buffer fills up
+	ro.BufferSize.Set(60)
+	report = a.health(now)
+	assert.False(t, report.Healthy)
+	assert.False(t, report.Outputs[0].Healthy)
+	assert.Equal(t, []string{"buffer 60% full"}, report.Outputs[0].Reasons)
+	ro.BufferSize.Set(0)
+
+	// the output stops writing
+	report = a.health(now.Add(2 * time.Minute))
+	assert.False(t, report.Healthy)
+	assert.Len(t, report.Outputs[0].Reasons, 1)
+
+	// the input fails, and recovers after health_max_error_age
+	input.RecordError(errors.New("connection refused"))
+	report = a.health(time.Now())
+	assert.False(t, report.Healthy)
+	assert.False(t, report.Inputs[0].Healthy)
+	assert.Equal(t, "connection refused", report.Inputs[0].LastError)
+
+	ro.LastWrite.Set(time.Now().UnixNano())
+	report = a.health(time.Now().Add(2 * time.Minute))
+	assert.True(t, report.Inputs[0].Healthy)
+	assert.Equal(t, "connection refused", report.Inputs[0].LastError)
+}
+
+func TestHealthNeverWritten(t *testing.T) {
+	a := newHealthAgent(t)
+	a.Config.Outputs[0].LastWrite.Set(0)
+
+	// outputs are measured from when they connected
+	assert.True(t, a.health(time.Now()).Outputs[0].Healthy)
+	assert.False(t, a.health(time.Now().Add(2*time.Minute)).Outputs[0].Healthy)
+}
+
+func TestServeHealth(t *testing.T) {
+	a := newHealthAgent(t)
+	a.Config.Outputs[0].BufferSize.Set(0)
+	a.Config.Outputs[0].LastWrite.Set(time.Now().UnixNano())
+	a.Config.Inputs[0].LastError.Set(0)
+
+	w := httptest.NewRecorder()
+	a.serveHealth(w, httptest.NewRequest("GET", "/health", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var report healthReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.True(t, report.Healthy)
+	assert.Equal(t, "health_test", report.Outputs[0].Alias)
+
+	a.Config.Outputs[0].BufferSize.Set(100)
+	w = httptest.NewRecorder()
+	a.serveHealth(w, httptest.NewRequest("GET", "/health", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	a.Config.Outputs[0].BufferSize.Set(0)
+}
+
+func TestServeReady(t *testing.T) {
+	a := newHealthAgent(t)
+
+	w := httptest.NewRecorder()
+	a.serveReady(w, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	a.running = 1
+	w = httptest.NewRecorder()
+	a.serveReady(w, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+type failingOutput struct {
+	countingOutput
+}
+
+func (o *failingOutput) Write(metrics []telegraf.Metric) error {
+	return errors.New("unavailable")
+}
+
+func TestHealthDiskBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "health")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := newReloadConfig()
+	c.Agent.HealthMaxBufferFill = 0.5
+	ro := models.NewRunningOutput("influxdb", &failingOutput{},
+		&models.OutputConfig{
+			Name:            "influxdb",
+			Alias:           "health_disk_test",
+			BufferDirectory: dir,
+			BufferMaxSize:   8192,
+		}, 10, 10)
+	c.Outputs = append(c.Outputs, ro)
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	require.NoError(t, a.Connect())
+	defer a.Close()
+
+	write := func(n int) {
+		for i := 0; i < n; i++ {
+			for j := 0; j < 10; j++ {
+				ro.AddMetric(testutil.TestMetric(j))
+			}
+			ro.Write()
+		}
+	}
+
+	// the disk buffer holds more metrics than metric_buffer_limit
+	write(3)
+	report := a.health(time.Now())
+	require.Len(t, report.Outputs, 1)
+	assert.True(t, report.Outputs[0].BufferSize > report.Outputs[0].BufferLimit)
+	assert.True(t, report.Outputs[0].BufferFill < 0.5)
+	assert.True(t, report.Healthy)
+
+	// until buffer_max_size is half used
+	write(10)
+	report = a.health(time.Now())
+	assert.True(t, report.Outputs[0].BufferFill > 0.5)
+	assert.False(t, report.Healthy)
+}
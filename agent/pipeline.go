@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// pipeline passes metrics through the processors. Each processor runs in its
+// own worker goroutines, connected to the next processor by bounded channels,
+// so a slow processor blocks the inputs instead of queueing without bound.
+//
+// When a processor has more than one worker, metrics are sharded between the
+// workers by series. All metrics of a series are handled by the same worker,
+// so the metrics of a series leave the pipeline in the order they entered it.
+type pipeline struct {
+	stages []*stage
+	out    chan telegraf.Metric
+}
+
+// stage is a processor and the queues in front of its workers.
+type stage struct {
+	processor *models.RunningProcessor
+	queues    []chan telegraf.Metric
+
+	QueueDepth selfstat.Stat
+
+	wg sync.WaitGroup
+}
+
+// newPipeline returns a pipeline that writes the processed metrics to out.
+//   workers is the number of goroutines running each processor. Processors
+//   must be safe for concurrent use when workers is greater than 1.
+func newPipeline(
+	processors []*models.RunningProcessor,
+	workers int,
+	queueSize int,
+	out chan telegraf.Metric,
+) *pipeline {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 1 {
+		queueSize = models.DEFAULT_PROCESSOR_QUEUE_SIZE
+	}
+
+	p := &pipeline{out: out}
+	for _, processor := range processors {
+		s := &stage{
+			processor: processor,
+			queues:    make([]chan telegraf.Metric, workers),
+			QueueDepth: selfstat.Register(
+				"process",
+				"queue_depth",
+				processor.StatTags(),
+			),
+		}
+		for i := range s.queues {
+			s.queues[i] = make(chan telegraf.Metric, queueSize)
+		}
+		p.stages = append(p.stages, s)
+	}
+	return p
+}
+
+// start starts the workers of every processor.
+func (p *pipeline) start() {
+	for i, s := range p.stages {
+		var next *stage
+		if i < len(p.stages)-1 {
+			next = p.stages[i+1]
+		}
+
+		s.wg.Add(len(s.queues))
+		for _, queue := range s.queues {
+			go p.work(s, queue, next)
+		}
+	}
+}
+
+func (p *pipeline) work(s *stage, queue chan telegraf.Metric, next *stage) {
+	defer s.wg.Done()
+	for m := range queue {
+		s.QueueDepth.Incr(-1)
+		for _, processed := range s.processor.Apply(m) {
+			if next != nil {
+				next.add(processed)
+			} else {
+				p.out <- processed
+			}
+		}
+	}
+}
+
+// add queues a metric in front of the worker handling its series, blocking
+// while that queue is full.
+func (s *stage) add(m telegraf.Metric) {
+	queue := s.queues[0]
+	if len(s.queues) > 1 {
+		queue = s.queues[m.HashID()%uint64(len(s.queues))]
+	}
+	s.QueueDepth.Incr(1)
+	queue <- m
+}
+
+// add passes a metric into the pipeline.
+func (p *pipeline) add(m telegraf.Metric) {
+	if len(p.stages) == 0 {
+		p.out <- m
+		return
+	}
+	p.stages[0].add(m)
+}
+
+// stop waits for every queued metric to go through the pipeline, and closes
+// the output channel. add must not be called after stop.
+func (p *pipeline) stop() {
+	for _, s := range p.stages {
+		for _, queue := range s.queues {
+			close(queue)
+		}
+		s.wg.Wait()
+	}
+	close(p.out)
+}
@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tagProcessor adds a tag to every metric, optionally sleeping to simulate a
+// slow processor.
+type tagProcessor struct {
+	key, value string
+	delay      time.Duration
+}
+
+func (p *tagProcessor) SampleConfig() string { return "" }
+func (p *tagProcessor) Description() string  { return "" }
+func (p *tagProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	time.Sleep(p.delay)
+	for _, m := range in {
+		m.AddTag(p.key, p.value)
+	}
+	return in
+}
+
+func newTagProcessor(name string, order int64, p *tagProcessor) *models.RunningProcessor {
+	return &models.RunningProcessor{
+		Name:      name,
+		Processor: p,
+		Config:    &models.ProcessorConfig{Name: name, Order: order},
+	}
+}
+
+func testMetric(t *testing.T, series string, i int) telegraf.Metric {
+	m, err := metric.New("test",
+		map[string]string{"series": series},
+		map[string]interface{}{"i": int64(i)},
+		time.Unix(0, int64(i)),
+	)
+	require.NoError(t, err)
+	return m
+}
+
+func TestPipelineAppliesProcessorsInOrder(t *testing.T) {
+	out := make(chan telegraf.Metric, 10)
+	p := newPipeline([]*models.RunningProcessor{
+		newTagProcessor("first", 1, &tagProcessor{key: "a", value: "1"}),
+		newTagProcessor("second", 2, &tagProcessor{key: "a", value: "2"}),
+	}, 1, 0, out)
+	p.start()
+
+	p.add(testMetric(t, "foo", 1))
+	p.stop()
+
+	var got []telegraf.Metric
+	for m := range out {
+		got = append(got, m)
+	}
+	require.Len(t, got, 1)
+	assert.Equal(t, "2", got[0].Tags()["a"])
+}
+
+func TestPipelineWithoutProcessors(t *testing.T) {
+	out := make(chan telegraf.Metric, 10)
+	p := newPipeline(nil, 4, 0, out)
+	p.start()
+
+	p.add(testMetric(t, "foo", 1))
+	p.stop()
+
+	assert.Len(t, out, 1)
+}
+
+func TestPipelineKeepsSeriesOrder(t *testing.T) {
+	out := make(chan telegraf.Metric, 1000)
+	p := newPipeline([]*models.RunningProcessor{
+		newTagProcessor("first", 1,
+			&tagProcessor{key: "a", value: "1", delay: time.Microsecond}),
+		newTagProcessor("second", 2, &tagProcessor{key: "b", value: "2"}),
+	}, 4, 2, out)
+	p.start()
+
+	for i := 0; i < 100; i++ {
+		for s := 0; s < 8; s++ {
+			p.add(testMetric(t, fmt.Sprintf("series%d", s), i))
+		}
+	}
+	p.stop()
+
+	last := map[string]int64{}
+	n := 0
+	for m := range out {
+		n++
+		series := m.Tags()["series"]
+		i := m.Fields()["i"].(int64)
+		if prev, ok := last[series]; ok {
+			assert.True(t, i > prev, "series %s out of order: %d after %d",
+				series, i, prev)
+		}
+		last[series] = i
+	}
+	assert.Equal(t, 800, n)
+	assert.Equal(t, int64(0), p.stages[0].QueueDepth.Get())
+}
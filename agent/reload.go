@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"log"
+	"reflect"
+
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+)
+
+// Reload replaces the configuration of the agent with c. It must be called
+// after Run has returned, and before Run is called again.
+//
+// Inputs and outputs whose configuration did not change are carried over:
+// service inputs keep running, and outputs keep their connection and the
+// metrics waiting in their buffer. The other inputs and outputs are stopped,
+// and the new outputs are connected.
+func (a *Agent) Reload(c *config.Config) error {
+	if err := setHostname(c); err != nil {
+		return err
+	}
+
+	// inputs are carried over only if the tags added to their metrics are
+	// the same.
+	oldInputs := append([]*models.RunningInput{}, a.Config.Inputs...)
+	keptInputs := 0
+	if reflect.DeepEqual(a.Config.Tags, c.Tags) {
+		for i, input := range c.Inputs {
+			for j, old := range oldInputs {
+				if old != nil && old.Config.Name == input.Config.Name &&
+					old.Checksum == input.Checksum {
+					c.Inputs[i] = old
+					oldInputs[j] = nil
+					keptInputs++
+					break
+				}
+			}
+		}
+	}
+	for _, old := range oldInputs {
+		if old != nil {
+			a.stopInput(old)
+		}
+	}
+
+	// outputs are carried over only if their buffers have the same size.
+	oldOutputs := append([]*models.RunningOutput{}, a.Config.Outputs...)
+	kept := make(map[*models.RunningOutput]bool)
+	if a.Config.Agent.MetricBatchSize == c.Agent.MetricBatchSize &&
+		a.Config.Agent.MetricBufferLimit == c.Agent.MetricBufferLimit {
+		for i, o := range c.Outputs {
+			for j, old := range oldOutputs {
+				if old != nil && old.Name == o.Name &&
+					old.Checksum == o.Checksum {
+					c.Outputs[i] = old
+					oldOutputs[j] = nil
+					kept[old] = true
+					break
+				}
+			}
+		}
+	}
+	for _, old := range oldOutputs {
+		if old != nil {
+			a.mu.Lock()
+			delete(a.connected, old)
+			a.mu.Unlock()
+			if err := closeOutput(old); err != nil {
+				log.Printf("E! Error closing output [%s]: %s\n",
+					old.LogName(), err)
+			}
+		}
+	}
+
+	a.mu.Lock()
+	a.Config = c
+	a.mu.Unlock()
+	for _, o := range c.Outputs {
+		if kept[o] {
+			continue
+		}
+		if err := connectOutput(o); err != nil {
+			return err
+		}
+		a.setConnected(o)
+	}
+
+	log.Printf("I! Reloaded config, kept %d of %d inputs and %d of %d outputs\n",
+		keptInputs, len(c.Inputs), len(kept), len(c.Outputs))
+	return nil
+}
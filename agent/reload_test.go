@@ -0,0 +1,148 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type serviceInput struct {
+	started, stopped int
+}
+
+func (i *serviceInput) SampleConfig() string                  { return "" }
+func (i *serviceInput) Description() string                   { return "" }
+func (i *serviceInput) Gather(acc telegraf.Accumulator) error { return nil }
+func (i *serviceInput) Start(acc telegraf.Accumulator) error {
+	i.started++
+	return nil
+}
+func (i *serviceInput) Stop() {
+	i.stopped++
+}
+
+type countingOutput struct {
+	connected, closed int
+}
+
+func (o *countingOutput) SampleConfig() string                  { return "" }
+func (o *countingOutput) Description() string                   { return "" }
+func (o *countingOutput) Write(metrics []telegraf.Metric) error { return nil }
+func (o *countingOutput) Connect() error {
+	o.connected++
+	return nil
+}
+func (o *countingOutput) Close() error {
+	o.closed++
+	return nil
+}
+
+func addInput(c *config.Config, name string, sum uint64) *serviceInput {
+	input := &serviceInput{}
+	ri := models.NewRunningInput(input, &models.InputConfig{Name: name})
+	ri.Checksum = sum
+	c.Inputs = append(c.Inputs, ri)
+	return input
+}
+
+func addOutput(c *config.Config, name string, sum uint64) *countingOutput {
+	output := &countingOutput{}
+	ro := models.NewRunningOutput(name, output,
+		&models.OutputConfig{Name: name}, 0, 0)
+	ro.Checksum = sum
+	c.Outputs = append(c.Outputs, ro)
+	return output
+}
+
+func newReloadConfig() *config.Config {
+	c := config.NewConfig()
+	c.Agent.OmitHostname = true
+	c.Agent.RoundInterval = false
+	return c
+}
+
+// runOnce starts the service inputs of the agent.
+func runOnce(t *testing.T, a *Agent) {
+	shutdown := make(chan struct{})
+	close(shutdown)
+	require.NoError(t, a.Run(shutdown))
+}
+
+func TestReloadKeepsUnchangedPlugins(t *testing.T) {
+	c := newReloadConfig()
+	statsd := addInput(c, "statsd", 1)
+	changed := addInput(c, "tcp_listener", 2)
+	influxdb := addOutput(c, "influxdb", 1)
+	file := addOutput(c, "file", 2)
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	require.NoError(t, a.Connect())
+	runOnce(t, a)
+
+	ro := c.Outputs[0]
+	ro.MetricsWritten.Set(0)
+	ro.AddMetric(testutil.TestMetric(1))
+
+	next := newReloadConfig()
+	newStatsd := addInput(next, "statsd", 1)
+	newChanged := addInput(next, "tcp_listener", 3)
+	newInfluxdb := addOutput(next, "influxdb", 1)
+	newFile := addOutput(next, "file", 3)
+	require.NoError(t, a.Reload(next))
+
+	assert.Equal(t, next, a.Config)
+	assert.Equal(t, c.Inputs[0], a.Config.Inputs[0])
+	assert.Equal(t, ro, a.Config.Outputs[0])
+
+	// unchanged plugins are not restarted
+	assert.Equal(t, 0, statsd.stopped)
+	assert.Equal(t, 0, newStatsd.started)
+	assert.Equal(t, 0, influxdb.closed)
+	assert.Equal(t, 0, newInfluxdb.connected)
+
+	// changed plugins are
+	assert.Equal(t, 1, changed.stopped)
+	assert.Equal(t, 1, file.closed)
+	assert.Equal(t, 1, newFile.connected)
+
+	runOnce(t, a)
+	assert.Equal(t, 1, statsd.started)
+	assert.Equal(t, 1, newChanged.started)
+
+	// the kept output still has its buffered metric
+	require.NoError(t, ro.Write())
+	assert.Equal(t, int64(1), ro.MetricsWritten.Get())
+
+	require.NoError(t, a.Close())
+	assert.Equal(t, 1, statsd.stopped)
+	assert.Equal(t, 1, newChanged.stopped)
+	assert.Equal(t, 1, influxdb.closed)
+}
+
+func TestReloadRestartsInputsWhenTagsChange(t *testing.T) {
+	c := newReloadConfig()
+	statsd := addInput(c, "statsd", 1)
+	addOutput(c, "file", 1)
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	require.NoError(t, a.Connect())
+	runOnce(t, a)
+
+	next := newReloadConfig()
+	next.Tags["dc"] = "us-east-1"
+	addInput(next, "statsd", 1)
+	addOutput(next, "file", 1)
+	require.NoError(t, a.Reload(next))
+
+	assert.Equal(t, 1, statsd.stopped)
+	assert.NotEqual(t, c.Inputs[0], a.Config.Inputs[0])
+	assert.Equal(t, c.Outputs[0], a.Config.Outputs[0])
+}
@@ -0,0 +1,11 @@
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// reopenSignal makes telegraf reopen its logfile.
+var reopenSignal os.Signal = syscall.SIGUSR1
@@ -0,0 +1,8 @@
+// +build windows
+
+package main
+
+import "os"
+
+// reopenSignal is not supported on windows.
+var reopenSignal os.Signal
@@ -11,6 +11,7 @@ import (
 	"runtime"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/influxdata/telegraf/agent"
 	"github.com/influxdata/telegraf/internal/config"
@@ -34,6 +35,18 @@ var fTest = flag.Bool("test", false, "gather metrics, print them out, and exit")
 var fConfig = flag.String("config", "", "configuration file to load")
 var fConfigDirectory = flag.String("config-directory", "",
 	"directory containing additional *.conf files")
+var fConfigToken = flag.String("config-token", os.Getenv("TELEGRAF_CONFIG_TOKEN"),
+	"bearer token sent when loading the config from a URL")
+var fConfigSSLCA = flag.String("config-ssl-ca", "",
+	"CA file used when loading the config from a URL")
+var fConfigSSLCert = flag.String("config-ssl-cert", "",
+	"client certificate used when loading the config from a URL")
+var fConfigSSLKey = flag.String("config-ssl-key", "",
+	"client key used when loading the config from a URL")
+var fConfigInsecureSkipVerify = flag.Bool("config-insecure-skip-verify", false,
+	"skip verification of the server certificate when loading the config from a URL")
+var fConfigPollInterval = flag.Duration("config-poll-interval", time.Minute,
+	"how often to check the config loaded from a URL for changes, 0 to disable")
 var fVersion = flag.Bool("version", false, "display the version")
 var fSampleConfig = flag.Bool("sample-config", false,
 	"print out full sample configuration")
@@ -82,9 +95,18 @@ Usage:
 The commands & flags are:
 
   config             print out full sample configuration to stdout
+  config check       validate the configuration files without running any
+                     plugin, and exit nonzero if a problem is found
   version            print the version to stdout
 
-  --config <file>     configuration file to load
+  --config <file>     configuration file to load, or http(s) URL to fetch it from
+  --config-token      bearer token sent when fetching the configuration from a
+                      URL, defaults to $TELEGRAF_CONFIG_TOKEN
+  --config-ssl-ca, --config-ssl-cert, --config-ssl-key, --config-insecure-skip-verify
+                      TLS settings used when fetching the configuration from a URL
+  --config-poll-interval
+                      how often to check the configuration fetched from a URL
+                      for changes, and reload it when it changed (default 1m)
   --test              gather metrics once, print them to stdout, and exit
   --config-directory  directory containing additional *.conf files
   --input-filter      filter the input plugins to enable, separator is :
@@ -102,6 +124,9 @@ Examples:
   # generate config with only cpu input & influxdb output plugins defined
   telegraf --input-filter cpu --output-filter influxdb config
 
+  # check a config file and config directory for errors and unknown options
+  telegraf --config telegraf.conf --config-directory telegraf.d config check
+
   # run a single telegraf collection, outputing metrics to stdout
   telegraf --config telegraf.conf -test
 
@@ -124,75 +149,88 @@ func reloadLoop(
 	aggregatorFilters []string,
 	processorFilters []string,
 ) {
+	var ag *agent.Agent
 	reload := make(chan bool, 1)
 	reload <- true
 	for <-reload {
 		reload <- false
 
 		// If no other options are specified, load the config file and run.
-		c := config.NewConfig()
-		c.OutputFilters = outputFilters
-		c.InputFilters = inputFilters
-		err := c.LoadConfig(*fConfig)
-		if err != nil {
+		c, err := loadConfig(inputFilters, outputFilters)
+		switch {
+		case err != nil && ag == nil:
 			log.Fatal("E! " + err.Error())
-		}
-
-		if *fConfigDirectory != "" {
-			err = c.LoadDirectory(*fConfigDirectory)
+		case err != nil:
+			// keep running with the current config
+			log.Printf("E! Error reloading config, keeping the running "+
+				"config: %s\n", err)
+		case ag == nil:
+			ag, err = agent.NewAgent(c)
 			if err != nil {
 				log.Fatal("E! " + err.Error())
 			}
-		}
-		if !*fTest && len(c.Outputs) == 0 {
-			log.Fatalf("E! Error: no outputs found, did you provide a valid config file?")
-		}
-		if len(c.Inputs) == 0 {
-			log.Fatalf("E! Error: no inputs found, did you provide a valid config file?")
-		}
 
-		ag, err := agent.NewAgent(c)
-		if err != nil {
-			log.Fatal("E! " + err.Error())
-		}
+			// Setup logging
+			setupLogging(ag.Config)
 
-		// Setup logging
-		logger.SetupLogging(
-			ag.Config.Agent.Debug || *fDebug,
-			ag.Config.Agent.Quiet || *fQuiet,
-			ag.Config.Agent.Logfile,
-		)
+			if *fTest {
+				err = ag.Test()
+				if err != nil {
+					log.Fatal("E! " + err.Error())
+				}
+				os.Exit(0)
+			}
 
-		if *fTest {
-			err = ag.Test()
+			err = ag.Connect()
+			if err != nil {
+				log.Fatal("E! " + err.Error())
+			}
+		default:
+			// only the plugins whose config changed are restarted
+			err = ag.Reload(c)
 			if err != nil {
 				log.Fatal("E! " + err.Error())
 			}
-			os.Exit(0)
-		}
 
-		err = ag.Connect()
-		if err != nil {
-			log.Fatal("E! " + err.Error())
+			setupLogging(ag.Config)
 		}
+		c = ag.Config
 
 		shutdown := make(chan struct{})
-		signals := make(chan os.Signal)
-		signal.Notify(signals, os.Interrupt, syscall.SIGHUP)
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+		if reopenSignal != nil {
+			signal.Notify(signals, reopenSignal)
+		}
+		changed := c.WatchRemote(*fConfigPollInterval, shutdown)
 		go func() {
-			select {
-			case sig := <-signals:
-				if sig == os.Interrupt {
-					close(shutdown)
-				}
-				if sig == syscall.SIGHUP {
+			for {
+				select {
+				case <-changed:
 					log.Printf("I! Reloading Telegraf config\n")
 					<-reload
 					reload <- true
 					close(shutdown)
+				case sig := <-signals:
+					if sig == reopenSignal {
+						if err := logger.Reopen(); err != nil {
+							log.Printf("E! Unable to reopen logfile: %s", err)
+						}
+						continue
+					}
+					if sig == os.Interrupt || sig == syscall.SIGTERM {
+						close(shutdown)
+					}
+					if sig == syscall.SIGHUP {
+						log.Printf("I! Reloading Telegraf config\n")
+						<-reload
+						reload <- true
+						close(shutdown)
+					}
+				case <-stop:
+					close(shutdown)
 				}
-			case <-stop:
-				close(shutdown)
+				return
 			}
 		}()
 
@@ -220,7 +258,83 @@ func reloadLoop(
 		}
 
 		ag.Run(shutdown)
+		signal.Stop(signals)
+	}
+	ag.Close()
+}
+
+// checkConfig validates the config file and the config directory, and prints
+// the problems found. It returns the exit code of 'telegraf config check'.
+func checkConfig() int {
+	c := newConfig()
+	problems := c.CheckConfig(*fConfig)
+	if *fConfigDirectory != "" {
+		problems = append(problems, c.CheckDirectory(*fConfigDirectory)...)
+	}
+
+	for _, p := range problems {
+		fmt.Fprintln(os.Stderr, p)
+	}
+	if len(problems) > 0 {
+		return 1
+	}
+	fmt.Println("Configuration is valid")
+	return 0
+}
+
+// newConfig returns a config set up to load config files from URLs.
+func newConfig() *config.Config {
+	c := config.NewConfig()
+	c.Remote = config.RemoteConfig{
+		Token:              *fConfigToken,
+		SSLCA:              *fConfigSSLCA,
+		SSLCert:            *fConfigSSLCert,
+		SSLKey:             *fConfigSSLKey,
+		InsecureSkipVerify: *fConfigInsecureSkipVerify,
 	}
+	return c
+}
+
+// setupLogging configures the logging output with the agent config, where the
+// --debug and --quiet flags take precedence.
+func setupLogging(c *config.Config) {
+	logger.SetupLogging(logger.LogConfig{
+		Debug:           c.Agent.Debug || *fDebug,
+		Quiet:           c.Agent.Quiet || *fQuiet,
+		Logfile:         c.Agent.Logfile,
+		Format:          c.Agent.LogFormat,
+		TimestampFormat: c.Agent.LogTimestampFormat,
+
+		RotationInterval:    c.Agent.LogfileRotationInterval.Duration,
+		RotationMaxSize:     c.Agent.LogfileRotationMaxSize,
+		RotationMaxAge:      c.Agent.LogfileRotationMaxAge.Duration,
+		RotationMaxArchives: c.Agent.LogfileRotationMaxArchives,
+	})
+}
+
+// loadConfig loads the config file and the config directory.
+func loadConfig(inputFilters, outputFilters []string) (*config.Config, error) {
+	c := newConfig()
+	c.OutputFilters = outputFilters
+	c.InputFilters = inputFilters
+	err := c.LoadConfig(*fConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if *fConfigDirectory != "" {
+		err = c.LoadDirectory(*fConfigDirectory)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !*fTest && len(c.Outputs) == 0 {
+		return nil, fmt.Errorf("Error: no outputs found, did you provide a valid config file?")
+	}
+	if len(c.Inputs) == 0 {
+		return nil, fmt.Errorf("Error: no inputs found, did you provide a valid config file?")
+	}
+	return c, nil
 }
 
 func usageExit(rc int) {
@@ -298,6 +412,12 @@ func main() {
 			fmt.Printf("Telegraf v%s (git: %s %s)\n", version, branch, commit)
 			return
 		case "config":
+			if len(args) > 1 && args[1] == "check" {
+				// allow flags after the command, ie,
+				// 'telegraf config check --config telegraf.conf'
+				flag.CommandLine.Parse(args[2:])
+				os.Exit(checkConfig())
+			}
 			config.PrintSampleConfig(
 				inputFilters,
 				outputFilters,
@@ -1,5 +1,7 @@
 package telegraf
 
+import "context"
+
 type Input interface {
 	// SampleConfig returns the default configuration of the Input
 	SampleConfig() string
@@ -12,6 +14,16 @@ type Input interface {
 	Gather(Accumulator) error
 }
 
+// ContextInput is an Input that stops gathering when its context is done,
+// when the gather times out or telegraf shuts down. The agent calls
+// GatherContext instead of Gather for these inputs.
+type ContextInput interface {
+	Input
+
+	// GatherContext is Gather, cancelled with ctx.
+	GatherContext(ctx context.Context, acc Accumulator) error
+}
+
 type ServiceInput interface {
 	// SampleConfig returns the default configuration of the Input
 	SampleConfig() string
@@ -38,7 +38,8 @@ func (b *Buffer) Len() int {
 	return len(b.buf)
 }
 
-// Add adds metrics to the buffer.
+// Add adds metrics to the buffer. The oldest metrics dropped from a full
+// buffer are rejected.
 func (b *Buffer) Add(metrics ...telegraf.Metric) {
 	for i, _ := range metrics {
 		MetricsWritten.Incr(1)
@@ -47,7 +48,8 @@ func (b *Buffer) Add(metrics ...telegraf.Metric) {
 		default:
 			b.mu.Lock()
 			MetricsDropped.Incr(1)
-			<-b.buf
+			dropped := <-b.buf
+			dropped.Reject()
 			b.buf <- metrics[i]
 			b.mu.Unlock()
 		}
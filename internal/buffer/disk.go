@@ -0,0 +1,616 @@
+package buffer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+const (
+	// DefaultDiskBufferMaxSize is the default maximum number of bytes that a
+	// DiskBuffer will keep on disk.
+	DefaultDiskBufferMaxSize = 128 * 1024 * 1024
+
+	// DefaultSegmentSize is the size at which a DiskBuffer rotates to a new
+	// segment file.
+	DefaultSegmentSize = 4 * 1024 * 1024
+
+	segmentExt    = ".seg"
+	cursorFile    = "cursor"
+	cursorTmpFile = "cursor.tmp"
+
+	// every record is prefixed by its payload length and crc32 checksum.
+	recordHeaderSize = 8
+)
+
+var errCorruptRecord = errors.New("corrupt record")
+
+// segment is a single append-only file of records.
+type segment struct {
+	id   uint64
+	path string
+	// size is the number of bytes written to the segment file.
+	size int64
+	// offset is the position of the first record not yet committed.
+	offset int64
+	// count is the number of records at or after offset.
+	count int
+}
+
+// DiskBuffer is a metric buffer backed by segment files on disk. Metrics that
+// are added to a DiskBuffer survive a restart, or a crash, of telegraf: when a
+// DiskBuffer is opened on an existing directory, every metric that had not
+// yet been committed is replayed.
+//
+// Metrics are read with Peek, and only removed from the disk by Commit once
+// they are written, so a batch being written when telegraf stops is written
+// again.
+//
+// Each record is checksummed. When a damaged or partially written record is
+// found while opening the buffer, the segment is truncated at that record.
+type DiskBuffer struct {
+	dir         string
+	maxSize     int64
+	segmentSize int64
+
+	// segments are ordered from oldest to newest, the last one is the segment
+	// that is currently appended to.
+	segments []*segment
+	w        *os.File
+	nextID   uint64
+
+	size  int64
+	count int
+
+	// peeked are the records returned by the last call to Peek, removed by
+	// Commit.
+	peeked []peekedRecords
+	closed bool
+
+	mu sync.Mutex
+}
+
+// peekedRecords are records at the read offset of a segment.
+type peekedRecords struct {
+	segment *segment
+	size    int64
+	count   int
+}
+
+// NewDiskBuffer opens, or creates, a DiskBuffer in the given directory.
+//   maxSize is the maximum number of bytes that DiskBuffer will keep on disk.
+//   If Add is called when the buffer is full, then the oldest segment will be
+//   dropped. A maxSize of 0 uses DefaultDiskBufferMaxSize.
+func NewDiskBuffer(dir string, maxSize int64) (*DiskBuffer, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultDiskBufferMaxSize
+	}
+	segmentSize := int64(DefaultSegmentSize)
+	if segmentSize > maxSize/2 {
+		segmentSize = maxSize / 2
+	}
+
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+
+	b := &DiskBuffer{
+		dir:         dir,
+		maxSize:     maxSize,
+		segmentSize: segmentSize,
+		nextID:      1,
+	}
+	if err := b.open(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// open loads the existing segments and the read cursor from disk.
+func (b *DiskBuffer) open() error {
+	files, err := ioutil.ReadDir(b.dir)
+	if err != nil {
+		return err
+	}
+
+	var ids []uint64
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), segmentExt) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(f.Name(), segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	cursorID, cursorOffset := b.readCursor()
+	for _, id := range ids {
+		path := b.segmentPath(id)
+		if id < cursorID {
+			// this segment was completely read before the last shutdown.
+			os.Remove(path)
+			continue
+		}
+
+		s := &segment{id: id, path: path}
+		if id == cursorID {
+			s.offset = cursorOffset
+		}
+		if err := b.scan(s); err != nil {
+			return err
+		}
+		if s.count == 0 {
+			os.Remove(path)
+			continue
+		}
+
+		b.segments = append(b.segments, s)
+		b.size += s.size - s.offset
+		b.count += s.count
+	}
+	if len(ids) > 0 {
+		b.nextID = ids[len(ids)-1] + 1
+	}
+
+	if b.count > 0 {
+		log.Printf("I! Disk buffer %s: replaying %d metrics", b.dir, b.count)
+	}
+	return nil
+}
+
+// scan verifies every record of the segment, counting the records after the
+// read offset. A segment with a damaged tail is truncated after the last
+// valid record.
+func (b *DiskBuffer) scan(s *segment) error {
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+
+	var pos int64
+	var total, before int
+	aligned := s.offset == 0
+	for pos < int64(len(data)) {
+		n, _, err := readRecord(data[pos:])
+		if err != nil {
+			log.Printf("W! Disk buffer %s: %s at offset %d of %s, truncating",
+				b.dir, err, pos, s.path)
+			if err := os.Truncate(s.path, pos); err != nil {
+				return err
+			}
+			break
+		}
+		if pos == s.offset {
+			aligned = true
+		}
+		if pos < s.offset {
+			before++
+		}
+		total++
+		pos += int64(n)
+	}
+	s.size = pos
+	if pos == s.offset {
+		aligned = true
+	}
+
+	// the cursor is invalid if it does not point to a record boundary, so
+	// replay the whole segment rather than guess.
+	if !aligned {
+		s.offset = 0
+		before = 0
+	}
+	s.count = total - before
+	return nil
+}
+
+// IsEmpty returns true if DiskBuffer is empty.
+func (b *DiskBuffer) IsEmpty() bool {
+	return b.Len() == 0
+}
+
+// Len returns the number of metrics in the buffer.
+func (b *DiskBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.count
+}
+
+// Size returns the number of bytes of the metrics in the buffer.
+func (b *DiskBuffer) Size() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.size
+}
+
+// MaxSize returns the maximum number of bytes the buffer keeps on disk.
+func (b *DiskBuffer) MaxSize() int64 {
+	return b.maxSize
+}
+
+// Add adds metrics to the buffer. The segment is synced to disk before Add
+// returns, the metrics are then accepted as they are not lost anymore.
+func (b *DiskBuffer) Add(metrics ...telegraf.Metric) {
+	if len(metrics) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		log.Printf("E! Disk buffer %s: unable to write %d metrics to a closed buffer",
+			b.dir, len(metrics))
+		MetricsDropped.Incr(int64(len(metrics)))
+		for _, m := range metrics {
+			m.Reject()
+		}
+		return
+	}
+
+	written := make([]telegraf.Metric, 0, len(metrics))
+	for i, _ := range metrics {
+		MetricsWritten.Incr(1)
+		if err := b.append(metrics[i]); err != nil {
+			log.Printf("E! Disk buffer %s: unable to write metric: %s", b.dir, err)
+			MetricsDropped.Incr(1)
+			metrics[i].Reject()
+			continue
+		}
+		written = append(written, metrics[i])
+	}
+
+	if b.w != nil {
+		if err := b.w.Sync(); err != nil {
+			log.Printf("E! Disk buffer %s: unable to sync segment: %s", b.dir, err)
+		}
+	}
+	for _, m := range written {
+		m.Accept()
+	}
+
+	for b.size > b.maxSize && len(b.segments) > 1 {
+		b.dropHead()
+	}
+}
+
+func (b *DiskBuffer) append(m telegraf.Metric) error {
+	if b.w == nil {
+		if err := b.newSegment(); err != nil {
+			return err
+		}
+	}
+
+	rec := encodeRecord(m)
+	if _, err := b.w.Write(rec); err != nil {
+		return err
+	}
+
+	s := b.segments[len(b.segments)-1]
+	s.size += int64(len(rec))
+	s.count++
+	b.size += int64(len(rec))
+	b.count++
+
+	if s.size >= b.segmentSize {
+		return b.closeSegment()
+	}
+	return nil
+}
+
+func (b *DiskBuffer) newSegment() error {
+	id := b.nextID
+	path := b.segmentPath(id)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+	b.nextID++
+	b.w = f
+	b.segments = append(b.segments, &segment{id: id, path: path})
+	return nil
+}
+
+// closeSegment syncs and closes the segment being written to, the next call
+// to Add will start a new segment.
+func (b *DiskBuffer) closeSegment() error {
+	if b.w == nil {
+		return nil
+	}
+	err := b.w.Sync()
+	if cerr := b.w.Close(); err == nil {
+		err = cerr
+	}
+	b.w = nil
+	return err
+}
+
+// dropHead removes the oldest segment, dropping all unread metrics in it.
+func (b *DiskBuffer) dropHead() {
+	s := b.segments[0]
+	MetricsDropped.Incr(int64(s.count))
+	b.removeHead()
+	b.writeCursor()
+}
+
+func (b *DiskBuffer) removeHead() {
+	s := b.segments[0]
+	// the peeked records of a removed segment cannot be committed anymore.
+	for i := range b.peeked {
+		if b.peeked[i].segment == s {
+			b.peeked[i].count = 0
+			b.peeked[i].size = 0
+		}
+	}
+	if len(b.segments) == 1 {
+		b.closeSegment()
+	}
+	b.segments = b.segments[1:]
+	b.size -= s.size - s.offset
+	b.count -= s.count
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		log.Printf("E! Disk buffer %s: unable to remove %s: %s", b.dir, s.path, err)
+	}
+}
+
+// Batch returns a batch of metrics of size batchSize.
+// the batch will be of maximum length batchSize. It can be less than batchSize,
+// if the length of DiskBuffer is less than batchSize.
+// Metrics returned by Batch are removed from the buffer.
+func (b *DiskBuffer) Batch(batchSize int) []telegraf.Metric {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := b.peek(batchSize)
+	b.commit()
+	return out
+}
+
+// Peek returns the oldest metrics of the buffer, at most batchSize, without
+// removing them. They are removed by the next call to Commit, once written.
+// Every call to Peek returns the same metrics until they are committed.
+func (b *DiskBuffer) Peek(batchSize int) []telegraf.Metric {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.peek(batchSize)
+}
+
+// Commit removes the metrics returned by the last call to Peek from the
+// buffer.
+func (b *DiskBuffer) Commit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.commit()
+}
+
+func (b *DiskBuffer) peek(batchSize int) []telegraf.Metric {
+	b.peeked = b.peeked[:0]
+	out := make([]telegraf.Metric, 0, min(b.count, batchSize))
+	for i := 0; len(out) < batchSize && i < len(b.segments); i++ {
+		s := b.segments[i]
+		if s.count == 0 {
+			continue
+		}
+
+		var p peekedRecords
+		var err error
+		out, p, err = b.readSegment(s, out, batchSize-len(out))
+		if err != nil {
+			log.Printf("E! Disk buffer %s: unable to read %s, dropping %d metrics: %s",
+				b.dir, s.path, s.count, err)
+			MetricsDropped.Incr(int64(s.count))
+			// only the head segment is removed, a damaged segment after it
+			// is dropped once it becomes the head.
+			if i == 0 {
+				b.removeHead()
+				b.writeCursor()
+				i--
+				continue
+			}
+			break
+		}
+		b.peeked = append(b.peeked, p)
+	}
+	return out
+}
+
+func (b *DiskBuffer) commit() {
+	for _, p := range b.peeked {
+		if p.count == 0 {
+			continue
+		}
+		s := p.segment
+		s.offset += p.size
+		s.count -= p.count
+		b.size -= p.size
+		b.count -= p.count
+	}
+	b.peeked = b.peeked[:0]
+
+	for len(b.segments) > 0 && b.segments[0].count == 0 {
+		b.removeHead()
+	}
+	b.writeCursor()
+}
+
+// readSegment appends up to n metrics from the read offset of the segment to
+// out, and returns the records read.
+func (b *DiskBuffer) readSegment(
+	s *segment,
+	out []telegraf.Metric,
+	n int,
+) ([]telegraf.Metric, peekedRecords, error) {
+	p := peekedRecords{segment: s}
+	f, err := os.Open(s.path)
+	if err != nil {
+		return out, p, err
+	}
+	defer f.Close()
+
+	data := make([]byte, s.size-s.offset)
+	if _, err := f.ReadAt(data, s.offset); err != nil && err != io.EOF {
+		return out, p, err
+	}
+
+	var pos int
+	for i := 0; i < n && p.count < s.count; i++ {
+		size, m, err := decodeRecord(data[pos:])
+		if err != nil {
+			return out, p, err
+		}
+		pos += size
+		p.size += int64(size)
+		p.count++
+		if m != nil {
+			out = append(out, m)
+		}
+	}
+	return out, p, nil
+}
+
+// Close syncs the buffer to disk and closes any open file. Metrics remaining
+// in the buffer will be replayed the next time the directory is opened.
+func (b *DiskBuffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	b.writeCursor()
+	return b.closeSegment()
+}
+
+func (b *DiskBuffer) segmentPath(id uint64) string {
+	return filepath.Join(b.dir, fmt.Sprintf("%020d%s", id, segmentExt))
+}
+
+// readCursor returns the segment id and offset of the first unread record.
+func (b *DiskBuffer) readCursor() (uint64, int64) {
+	data, err := ioutil.ReadFile(filepath.Join(b.dir, cursorFile))
+	if err != nil || len(data) != 16 {
+		return 0, 0
+	}
+	return binary.BigEndian.Uint64(data[0:8]), int64(binary.BigEndian.Uint64(data[8:16]))
+}
+
+func (b *DiskBuffer) writeCursor() {
+	path := filepath.Join(b.dir, cursorFile)
+	if len(b.segments) == 0 {
+		os.Remove(path)
+		return
+	}
+
+	s := b.segments[0]
+	data := make([]byte, 16)
+	binary.BigEndian.PutUint64(data[0:8], s.id)
+	binary.BigEndian.PutUint64(data[8:16], uint64(s.offset))
+	if err := b.replaceFile(path, data); err != nil {
+		log.Printf("E! Disk buffer %s: unable to write cursor: %s", b.dir, err)
+	}
+}
+
+// replaceFile atomically replaces the content of the file at path, so that a
+// crash leaves either the old or the new content.
+func (b *DiskBuffer) replaceFile(path string, data []byte) error {
+	tmp := filepath.Join(b.dir, cursorTmpFile)
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	// the rename itself is only durable once the directory is synced.
+	d, err := os.Open(b.dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// encodeRecord serializes a metric as a record:
+//   length (4 bytes) | crc32 (4 bytes) | value type (1 byte) | line protocol
+func encodeRecord(m telegraf.Metric) []byte {
+	line := m.Serialize()
+	rec := make([]byte, recordHeaderSize+1+len(line))
+	rec[recordHeaderSize] = byte(m.Type())
+	copy(rec[recordHeaderSize+1:], line)
+
+	payload := rec[recordHeaderSize:]
+	binary.BigEndian.PutUint32(rec[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(rec[4:8], crc32.ChecksumIEEE(payload))
+	return rec
+}
+
+// readRecord verifies the record at the start of buf, returning its size and
+// payload.
+func readRecord(buf []byte) (int, []byte, error) {
+	if len(buf) < recordHeaderSize {
+		return 0, nil, errCorruptRecord
+	}
+	n := int(binary.BigEndian.Uint32(buf[0:4]))
+	if n < 1 || len(buf) < recordHeaderSize+n {
+		return 0, nil, errCorruptRecord
+	}
+	payload := buf[recordHeaderSize : recordHeaderSize+n]
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(buf[4:8]) {
+		return 0, nil, errCorruptRecord
+	}
+	return recordHeaderSize + n, payload, nil
+}
+
+// decodeRecord returns the size of the record at the start of buf and the
+// metric it holds. A nil metric is returned, without error, for records that
+// pass the checksum but cannot be parsed as a metric.
+func decodeRecord(buf []byte) (int, telegraf.Metric, error) {
+	size, payload, err := readRecord(buf)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	metrics, err := metric.Parse(payload[1:])
+	if err != nil || len(metrics) != 1 {
+		return size, nil, nil
+	}
+	m := metrics[0]
+	if mType := telegraf.ValueType(payload[0]); mType != telegraf.Untyped {
+		m, err = metric.New(m.Name(), m.Tags(), m.Fields(), m.Time(), mType)
+		if err != nil {
+			return size, nil, nil
+		}
+	}
+	return size, m, nil
+}
@@ -0,0 +1,225 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "telegraf-buffer")
+	require.NoError(t, err)
+	return dir
+}
+
+func TestDiskBufferBasicFuncs(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	b, err := NewDiskBuffer(dir, 0)
+	require.NoError(t, err)
+	defer b.Close()
+	MetricsDropped.Set(0)
+	MetricsWritten.Set(0)
+
+	assert.True(t, b.IsEmpty())
+	assert.Zero(t, b.Len())
+
+	b.Add(metricList...)
+	assert.False(t, b.IsEmpty())
+	assert.Equal(t, 5, b.Len())
+	assert.Equal(t, int64(5), MetricsWritten.Get())
+
+	batch := b.Batch(3)
+	require.Len(t, batch, 3)
+	assert.Equal(t, "mymetric1", batch[0].Name())
+	assert.Equal(t, "mymetric3", batch[2].Name())
+	assert.Equal(t, metricList[0].Tags(), batch[0].Tags())
+	assert.Equal(t, metricList[0].Fields(), batch[0].Fields())
+	assert.Equal(t, metricList[0].Time(), batch[0].Time())
+	assert.Equal(t, 2, b.Len())
+
+	batch = b.Batch(10)
+	require.Len(t, batch, 2)
+	assert.Equal(t, "mymetric5", batch[1].Name())
+	assert.True(t, b.IsEmpty())
+	assert.Zero(t, MetricsDropped.Get())
+}
+
+func TestDiskBufferKeepsValueType(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	b, err := NewDiskBuffer(dir, 0)
+	require.NoError(t, err)
+	defer b.Close()
+
+	m, err := metric.New("cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"usage": 42.0},
+		time.Unix(0, 0),
+		telegraf.Counter,
+	)
+	require.NoError(t, err)
+	b.Add(m)
+
+	batch := b.Batch(1)
+	require.Len(t, batch, 1)
+	assert.Equal(t, telegraf.Counter, batch[0].Type())
+}
+
+func TestDiskBufferReplay(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	b, err := NewDiskBuffer(dir, 0)
+	require.NoError(t, err)
+	b.Add(metricList...)
+	assert.Len(t, b.Batch(2), 2)
+	require.NoError(t, b.Close())
+
+	b, err = NewDiskBuffer(dir, 0)
+	require.NoError(t, err)
+	defer b.Close()
+	assert.Equal(t, 3, b.Len())
+
+	batch := b.Batch(10)
+	require.Len(t, batch, 3)
+	assert.Equal(t, "mymetric3", batch[0].Name())
+	assert.Equal(t, "mymetric5", batch[2].Name())
+
+	// appends after a replay go to a new segment
+	b.Add(metricList[0])
+	assert.Equal(t, "mymetric1", b.Batch(1)[0].Name())
+}
+
+func TestDiskBufferDropsOldestSegment(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	size := int64(len(encodeRecord(testutil.TestMetric(0, "mymetric"))))
+	// segments hold two records, and the buffer keeps at most two segments.
+	b, err := NewDiskBuffer(dir, 4*size)
+	require.NoError(t, err)
+	defer b.Close()
+	MetricsDropped.Set(0)
+
+	for i := 0; i < 6; i++ {
+		b.Add(testutil.TestMetric(i, "mymetric"))
+	}
+	assert.Equal(t, 4, b.Len())
+	assert.Equal(t, int64(2), MetricsDropped.Get())
+
+	batch := b.Batch(10)
+	require.Len(t, batch, 4)
+	assert.Equal(t, int64(2), batch[0].Fields()["value"])
+	assert.Equal(t, int64(5), batch[3].Fields()["value"])
+}
+
+func TestDiskBufferTruncatesCorruptRecord(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	b, err := NewDiskBuffer(dir, 0)
+	require.NoError(t, err)
+	b.Add(metricList...)
+	require.NoError(t, b.Close())
+
+	// simulate a crash in the middle of writing a record
+	path := filepath.Join(dir, "00000000000000000001.seg")
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0640)
+	require.NoError(t, err)
+	_, err = f.Write(encodeRecord(metricList[0])[:10])
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	b, err = NewDiskBuffer(dir, 0)
+	require.NoError(t, err)
+	defer b.Close()
+	assert.Equal(t, 5, b.Len())
+	assert.Len(t, b.Batch(10), 5)
+}
+
+func TestDiskBufferPeekCommit(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	b, err := NewDiskBuffer(dir, 0)
+	require.NoError(t, err)
+	defer b.Close()
+
+	b.Add(metricList...)
+	batch := b.Peek(3)
+	require.Len(t, batch, 3)
+	assert.Equal(t, 5, b.Len())
+
+	// the same metrics are returned until they are committed
+	batch = b.Peek(3)
+	require.Len(t, batch, 3)
+	assert.Equal(t, "mymetric1", batch[0].Name())
+
+	b.Commit()
+	assert.Equal(t, 2, b.Len())
+	batch = b.Peek(3)
+	require.Len(t, batch, 2)
+	assert.Equal(t, "mymetric4", batch[0].Name())
+
+	// committing twice does not remove more metrics
+	b.Commit()
+	b.Commit()
+	assert.True(t, b.IsEmpty())
+}
+
+func TestDiskBufferReplaysUncommitted(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	b, err := NewDiskBuffer(dir, 0)
+	require.NoError(t, err)
+	b.Add(metricList...)
+	b.Peek(2)
+	b.Commit()
+	// telegraf stops while writing the next batch, without closing the
+	// buffer.
+	assert.Len(t, b.Peek(2), 2)
+
+	b2, err := NewDiskBuffer(dir, 0)
+	require.NoError(t, err)
+	defer b2.Close()
+	assert.Equal(t, 3, b2.Len())
+	batch := b2.Batch(10)
+	require.Len(t, batch, 3)
+	assert.Equal(t, "mymetric3", batch[0].Name())
+
+	// the cursor is replaced, never left half written
+	_, err = os.Stat(filepath.Join(dir, cursorTmpFile))
+	assert.True(t, os.IsNotExist(err))
+	b.Close()
+}
+
+func TestDiskBufferClosed(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	b, err := NewDiskBuffer(dir, 0)
+	require.NoError(t, err)
+	b.Add(metricList...)
+	b.Peek(2)
+	require.NoError(t, b.Close())
+
+	// a commit after close does not remove the metrics
+	b.Commit()
+	b, err = NewDiskBuffer(dir, 0)
+	require.NoError(t, err)
+	defer b.Close()
+	assert.Equal(t, 5, b.Len())
+}
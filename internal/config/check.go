@@ -0,0 +1,408 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf/plugins/aggregators"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/plugins/serializers"
+
+	"github.com/influxdata/toml"
+	"github.com/influxdata/toml/ast"
+)
+
+// Problem is an error found in a configuration file by CheckConfig.
+type Problem struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line == 0 {
+		return fmt.Sprintf("%s: %s", p.File, p.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+}
+
+// CheckConfig validates the config file at path the way LoadConfig loads it,
+// without starting any plugin. Instead of stopping at the first error, it
+// returns every problem found, including the options that are not used by
+// the plugin they are set on, which LoadConfig ignores.
+//
+// The secret references are only checked to be well-formed, they are not
+// resolved, and the config files are not fetched from URLs.
+func (c *Config) CheckConfig(path string) []Problem {
+	var err error
+	if path == "" {
+		if path, err = getDefaultConfigPath(); err != nil {
+			return []Problem{{Message: err.Error()}}
+		}
+	}
+	if isURL(path) {
+		return []Problem{{File: path,
+			Message: "config files are not fetched from URLs by config check"}}
+	}
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return []Problem{{File: path, Message: err.Error()}}
+	}
+	tbl, err := parseConfig(contents)
+	if err != nil {
+		return []Problem{{File: path, Message: err.Error()}}
+	}
+
+	problems := checkSecrets(path, tbl)
+	report := func(line int, format string, a ...interface{}) {
+		problems = append(problems, Problem{
+			File:    path,
+			Line:    line,
+			Message: fmt.Sprintf(format, a...),
+		})
+	}
+
+	for _, name := range sortedKeys(tbl.Fields) {
+		val := tbl.Fields[name]
+		switch name {
+		case "global_tags", "tags":
+			subTable, ok := val.(*ast.Table)
+			if !ok {
+				report(line(val), "[%s] must be a table", name)
+				continue
+			}
+			if err = toml.UnmarshalTable(subTable, c.Tags); err != nil {
+				report(subTable.Line, "[%s]: %s", name, err)
+			}
+		case "agent":
+			subTable, ok := val.(*ast.Table)
+			if !ok {
+				report(line(val), "[agent] must be a table")
+				continue
+			}
+			problems = append(problems,
+				checkFields(path, "agent.", subTable, reflect.TypeOf(c.Agent))...)
+			removeUnknownFields(subTable, reflect.TypeOf(c.Agent))
+			if err = toml.UnmarshalTable(subTable, c.Agent); err != nil {
+				report(subTable.Line, "[agent]: %s", err)
+			}
+		case "router":
+			subTables, ok := val.([]*ast.Table)
+			if !ok {
+				report(line(val), "router must be an array of tables, use [[router]]")
+				continue
+			}
+			for _, t := range subTables {
+				if err = c.addRoute(t); err != nil {
+					report(t.Line, "[[router]]: %s", err)
+				}
+			}
+		case "inputs", "plugins", "outputs", "processors", "aggregators":
+			subTable, ok := val.(*ast.Table)
+			if !ok {
+				report(line(val), "[%s] must be a table", name)
+				continue
+			}
+			kind := name
+			if kind == "plugins" {
+				kind = "inputs"
+			}
+			for _, pluginName := range sortedKeys(subTable.Fields) {
+				switch pluginVal := subTable.Fields[pluginName].(type) {
+				case *ast.Table:
+					problems = append(problems,
+						c.checkPlugin(path, kind, pluginName, pluginVal)...)
+				case []*ast.Table:
+					for _, t := range pluginVal {
+						problems = append(problems,
+							c.checkPlugin(path, kind, pluginName, t)...)
+					}
+				default:
+					report(line(pluginVal), "unsupported config format: %s.%s",
+						name, pluginName)
+				}
+			}
+		default:
+			// legacy config files without the [inputs] table
+			subTable, ok := val.(*ast.Table)
+			if !ok {
+				report(line(val), "unknown option %s", name)
+				continue
+			}
+			problems = append(problems,
+				c.checkPlugin(path, "inputs", name, subTable)...)
+		}
+	}
+	return problems
+}
+
+// checkSecrets returns a problem for each option of tbl and of its
+// sub-tables with a malformed secret reference, without resolving them.
+func checkSecrets(file string, tbl *ast.Table) []Problem {
+	var problems []Problem
+	walkStrings(tbl, func(line int, s *ast.String) error {
+		if err := checkString(s.Value); err != nil {
+			problems = append(problems, Problem{
+				File:    file,
+				Line:    line,
+				Message: err.Error(),
+			})
+		}
+		return nil
+	})
+	sort.Slice(problems, func(i, j int) bool {
+		return problems[i].Line < problems[j].Line
+	})
+	return problems
+}
+
+// CheckDirectory runs CheckConfig on every *.conf file of the directory.
+func (c *Config) CheckDirectory(path string) []Problem {
+	var problems []Problem
+	walkfn := func(thispath string, info os.FileInfo, err error) error {
+		if info == nil {
+			problems = append(problems, Problem{
+				File:    thispath,
+				Message: "Telegraf is not permitted to read this file",
+			})
+			return nil
+		}
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".conf") {
+			return nil
+		}
+		problems = append(problems, c.CheckConfig(thispath)...)
+		return nil
+	}
+	filepath.Walk(path, walkfn)
+	return problems
+}
+
+// checkPlugin adds the plugin defined by tbl to c, and returns the problems
+// found in its table.
+func (c *Config) checkPlugin(file, kind, name string, tbl *ast.Table) []Problem {
+	var problems []Problem
+
+	// the options that the plugin does not use are reported, and removed so
+	// that they do not fail the whole plugin. When the options common to
+	// every plugin are invalid, only that error is reported.
+	if plugin := newPlugin(kind, name); plugin != nil {
+		clone := &ast.Table{Fields: make(map[string]interface{})}
+		for key, val := range tbl.Fields {
+			clone.Fields[key] = val
+		}
+		if err := removeCommonOptions(kind, name, clone, plugin); err == nil {
+			problems = append(problems, checkFields(file, kind+"."+name+".",
+				clone, reflect.TypeOf(plugin))...)
+			for key := range clone.Fields {
+				if _, ok := findField(reflect.TypeOf(plugin), key); !ok {
+					delete(tbl.Fields, key)
+				}
+			}
+		}
+	}
+
+	var err error
+	switch kind {
+	case "inputs":
+		err = c.addInput(name, tbl)
+	case "outputs":
+		err = c.addOutput(name, tbl)
+	case "processors":
+		err = c.addProcessor(name, tbl)
+	case "aggregators":
+		err = c.addAggregator(name, tbl)
+	}
+	if err != nil {
+		problems = append(problems, Problem{
+			File:    file,
+			Line:    tbl.Line,
+			Message: fmt.Sprintf("[[%s.%s]]: %s", kind, name, err),
+		})
+	}
+	return problems
+}
+
+// newPlugin returns a new instance of the plugin, or nil if there is no such
+// plugin.
+func newPlugin(kind, name string) interface{} {
+	switch kind {
+	case "inputs":
+		// Legacy support renaming io input to diskio
+		if name == "io" {
+			name = "diskio"
+		}
+		if creator, ok := inputs.Inputs[name]; ok {
+			return creator()
+		}
+	case "outputs":
+		if creator, ok := outputs.Outputs[name]; ok {
+			return creator()
+		}
+	case "processors":
+		if creator, ok := processors.Processors[name]; ok {
+			return creator()
+		}
+	case "aggregators":
+		if creator, ok := aggregators.Aggregators[name]; ok {
+			return creator()
+		}
+	}
+	return nil
+}
+
+// removeCommonOptions removes from tbl the options handled by telegraf for
+// every plugin of the kind, leaving the options of the plugin itself.
+func removeCommonOptions(
+	kind, name string,
+	tbl *ast.Table,
+	plugin interface{},
+) error {
+	var err error
+	switch kind {
+	case "inputs":
+		if _, ok := plugin.(parsers.ParserInput); ok {
+			if _, err = buildParser(name, tbl); err != nil {
+				return err
+			}
+		}
+		_, err = buildInput(name, tbl)
+	case "outputs":
+		if _, ok := plugin.(serializers.SerializerOutput); ok {
+			if _, err = buildSerializer(name, tbl); err != nil {
+				return err
+			}
+		}
+		_, err = buildOutput(name, tbl)
+	case "processors":
+		_, err = buildProcessor(name, tbl)
+	case "aggregators":
+		_, err = buildAggregator(name, tbl)
+	}
+	return err
+}
+
+// removeUnknownFields removes the options of tbl that do not match a field
+// of typ.
+func removeUnknownFields(tbl *ast.Table, typ reflect.Type) {
+	for key := range tbl.Fields {
+		if _, ok := findField(typ, key); !ok {
+			delete(tbl.Fields, key)
+		}
+	}
+}
+
+// checkFields returns a problem for each option of tbl that does not match a
+// field of typ, and checks the sub-tables of the matching fields.
+func checkFields(file, prefix string, tbl *ast.Table, typ reflect.Type) []Problem {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		// maps and interfaces accept any key
+		return nil
+	}
+
+	var problems []Problem
+	for _, key := range sortedKeys(tbl.Fields) {
+		val := tbl.Fields[key]
+		field, ok := findField(typ, key)
+		if !ok {
+			problems = append(problems, Problem{
+				File:    file,
+				Line:    line(val),
+				Message: fmt.Sprintf("unknown option %s%s", prefix, key),
+			})
+			continue
+		}
+
+		switch v := val.(type) {
+		case *ast.Table:
+			problems = append(problems,
+				checkFields(file, prefix+key+".", v, field.Type)...)
+		case []*ast.Table:
+			elem := field.Type
+			if elem.Kind() == reflect.Slice || elem.Kind() == reflect.Array {
+				elem = elem.Elem()
+			}
+			for _, t := range v {
+				problems = append(problems,
+					checkFields(file, prefix+key+".", t, elem)...)
+			}
+		}
+	}
+	return problems
+}
+
+// findField returns the field of the struct typ that toml.UnmarshalTable
+// sets for the key: the field with a toml tag equal to the key, or else the
+// field whose name matches the key regardless of case and underscores.
+func findField(typ reflect.Type, key string) (reflect.StructField, bool) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.Anonymous {
+			if field, ok := findField(f.Type, key); ok {
+				return field, true
+			}
+		}
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		tag := strings.TrimSpace(strings.SplitN(f.Tag.Get("toml"), ",", 2)[0])
+		switch tag {
+		case "-":
+		case "":
+			if normalize(f.Name) == normalize(key) {
+				return f, true
+			}
+		default:
+			if tag == key {
+				return f, true
+			}
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func normalize(s string) string {
+	return strings.Replace(strings.ToLower(s), "_", "", -1)
+}
+
+// line returns the line on which a table or option is defined.
+func line(val interface{}) int {
+	switch v := val.(type) {
+	case *ast.KeyValue:
+		return v.Line
+	case *ast.Table:
+		return v.Line
+	case []*ast.Table:
+		if len(v) > 0 {
+			return v[0].Line
+		}
+	}
+	return 0
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
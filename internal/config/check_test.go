@@ -0,0 +1,91 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/plugins/inputs/exec"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_CheckConfig(t *testing.T) {
+	c := NewConfig()
+	problems := c.CheckConfig("./testdata/check.toml")
+
+	var lines []int
+	for _, p := range problems {
+		assert.Equal(t, "./testdata/check.toml", p.File)
+		lines = append(lines, p.Line)
+	}
+	assert.Equal(t, []int{3, 9, 5, 19, 13}, lines, "%v", problems)
+
+	require.Len(t, problems, 5)
+	assert.Equal(t, "./testdata/check.toml:3: unknown option agent.flush_intervl",
+		problems[0].String())
+	assert.Contains(t, problems[1].Message, "yaml")
+	assert.Contains(t, problems[2].Message, "5x")
+	assert.Equal(t, "unknown option inputs.memcached.server",
+		problems[3].Message)
+	assert.Contains(t, problems[4].Message, "namepass")
+
+	// the plugins are still loaded without their unknown options
+	require.Len(t, c.Inputs, 1)
+	assert.Equal(t, 5*time.Second, c.Inputs[0].Config.Interval)
+	assert.Len(t, c.Inputs[0].Config.Filter.TagPass, 1)
+}
+
+func TestConfig_CheckValidConfig(t *testing.T) {
+	c := NewConfig()
+	assert.Empty(t, c.CheckConfig("./testdata/single_plugin.toml"))
+	assert.Len(t, c.Inputs, 1)
+}
+
+func TestConfig_CheckConfigParseError(t *testing.T) {
+	c := NewConfig()
+	problems := c.CheckConfig("./testdata/missing.toml")
+	require.Len(t, problems, 1)
+	assert.Equal(t, "./testdata/missing.toml", problems[0].File)
+}
+
+func TestConfig_CheckConfigSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ran := filepath.Join(dir, "ran")
+	path := filepath.Join(dir, "telegraf.conf")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`
+[[inputs.exec]]
+  commands = ["@{exec:touch `+ran+`}"]
+  data_format = "influx"
+
+[[inputs.exec]]
+  commands = ["@{vault:secret/telegraf}"]
+  data_format = "influx"
+`), 0644))
+
+	c := NewConfig()
+	problems := c.CheckConfig(path)
+	require.Len(t, problems, 1)
+	assert.Equal(t, 7, problems[0].Line)
+	assert.Contains(t, problems[0].Message, "vault")
+
+	// the exec secret is not run
+	_, err = os.Stat(ran)
+	assert.True(t, os.IsNotExist(err))
+	require.Len(t, c.Inputs, 2)
+	assert.Equal(t, []string{"@{exec:touch " + ran + "}"},
+		c.Inputs[0].Input.(*exec.Exec).Commands)
+}
+
+func TestConfig_CheckConfigURL(t *testing.T) {
+	c := NewConfig()
+	problems := c.CheckConfig("http://localhost:8086/api/v2/telegrafs/1")
+	require.Len(t, problems, 1)
+	assert.Contains(t, problems[0].Message, "not fetched")
+}
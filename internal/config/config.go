@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"io"
 	"io/ioutil"
 	"log"
 	"math"
@@ -19,6 +21,7 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/logger"
 	"github.com/influxdata/telegraf/plugins/aggregators"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/outputs"
@@ -56,6 +59,13 @@ type Config struct {
 	Aggregators []*models.RunningAggregator
 	// Processors have a slice wrapper type because they need to be sorted
 	Processors models.RunningProcessors
+	// Routes is the routing table deciding which outputs receive a metric.
+	Routes []*models.RouteConfig
+
+	// Remote holds the settings used to load config files from URLs.
+	Remote RemoteConfig
+	// remotes are the config files that were loaded from URLs.
+	remotes []*remoteFile
 }
 
 func NewConfig() *Config {
@@ -65,6 +75,9 @@ func NewConfig() *Config {
 			Interval:      internal.Duration{Duration: 10 * time.Second},
 			RoundInterval: true,
 			FlushInterval: internal.Duration{Duration: 10 * time.Second},
+
+			ProcessorWorkers:   1,
+			ProcessorQueueSize: models.DEFAULT_PROCESSOR_QUEUE_SIZE,
 		},
 
 		Tags:          make(map[string]string),
@@ -99,6 +112,11 @@ type AgentConfig struct {
 	// same time, which can have a measurable effect on the system.
 	CollectionJitter internal.Duration
 
+	// CollectionSpread delays the gathering of each input without a
+	// collection_offset by an offset derived from its configuration, to
+	// spread the inputs with the same interval across the interval.
+	CollectionSpread bool
+
 	// FlushInterval is the Interval at which to flush data
 	FlushInterval internal.Duration
 
@@ -124,6 +142,20 @@ type AgentConfig struct {
 	// does _not_ deactivate FlushInterval.
 	FlushBufferWhenFull bool
 
+	// ShutdownTimeout bounds the time spent writing the buffered metrics at
+	// shutdown. The metrics that could not be written are then kept in the
+	// buffer directory of their output, or dropped. 0 waits for all writes.
+	ShutdownTimeout internal.Duration
+
+	// ProcessorWorkers is the number of goroutines running each processor.
+	// Metrics are distributed between the workers by series, so the order of
+	// the metrics of a series is kept.
+	ProcessorWorkers int
+
+	// ProcessorQueueSize is the number of metrics that can wait in front of
+	// each processor worker. Inputs are blocked while a queue is full.
+	ProcessorQueueSize int
+
 	// TODO(cam): Remove UTC and parameter, they are no longer
 	// valid for the agent config. Leaving them here for now for backwards-
 	// compatability
@@ -134,11 +166,39 @@ type AgentConfig struct {
 
 	// Logfile specifies the file to send logs to
 	Logfile string
+	// LogfileRotationInterval rotates the logfile when it is older.
+	LogfileRotationInterval internal.Duration
+	// LogfileRotationMaxSize rotates the logfile when it grows larger, in
+	// bytes.
+	LogfileRotationMaxSize int64
+	// LogfileRotationMaxAge removes the rotated logfiles that are older.
+	LogfileRotationMaxAge internal.Duration
+	// LogfileRotationMaxArchives is the number of rotated logfiles kept, all
+	// of them when zero.
+	LogfileRotationMaxArchives int
+
+	// LogFormat is the format of the log messages, "text" or "json".
+	LogFormat string
+	// LogTimestampFormat is the Go time layout of the log timestamps.
+	LogTimestampFormat string
 
 	// Quiet is the option for running in quiet mode
 	Quiet        bool
 	Hostname     string
 	OmitHostname bool
+
+	// HealthListen is the address of the HTTP server reporting the health of
+	// the agent on /health and /ready. The server is disabled when empty.
+	HealthListen string
+	// HealthMaxWriteAge makes an output unhealthy when its last successful
+	// write is older.
+	HealthMaxWriteAge internal.Duration
+	// HealthMaxBufferFill makes an output unhealthy when the ratio of its
+	// buffer in use is greater, between 0 and 1.
+	HealthMaxBufferFill float64
+	// HealthMaxErrorAge makes an input unhealthy when its last gather error
+	// is more recent.
+	HealthMaxErrorAge internal.Duration
 }
 
 // Inputs returns a list of strings of the configured inputs.
@@ -221,6 +281,10 @@ var header = `# Telegraf Configuration
   ## This can be used to avoid many plugins querying things like sysfs at the
   ## same time, which can have a measurable effect on the system.
   collection_jitter = "0s"
+  ## If true, each input without a collection_offset is gathered at a fixed
+  ## offset within its interval, derived from its configuration, so that
+  ## inputs with the same interval are not all gathered at the same time.
+  collection_spread = false
 
   ## Default flushing interval for all outputs. You shouldn't set this below
   ## interval. Maximum flush_interval will be flush_interval + flush_jitter
@@ -230,6 +294,18 @@ var header = `# Telegraf Configuration
   ## ie, a jitter of 5s and interval 10s means flushes will happen every 10-15s
   flush_jitter = "0s"
 
+  ## Maximum time spent writing the buffered metrics at shutdown, unlimited by
+  ## default. Metrics that could not be written by then are kept in the
+  ## buffer_directory of their output, if any, or dropped.
+  # shutdown_timeout = "30s"
+
+  ## Number of goroutines running each processor. Metrics of the same series
+  ## are always handled by the same goroutine, so their order is kept.
+  processor_workers = 1
+  ## Number of metrics that can be queued in front of each processor goroutine
+  ## before inputs are blocked.
+  processor_queue_size = 100
+
   ## By default or when set to "0s", precision will be set to the same
   ## timestamp order as the collection interval, with the maximum being 1s.
   ##   ie, when interval = "10s", precision will be "1s"
@@ -246,12 +322,39 @@ var header = `# Telegraf Configuration
   quiet = false
   ## Specify the log file name. The empty string means to log to stderr.
   logfile = ""
+  ## Rotate the log file when it is older than this duration, or larger than
+  ## this size in bytes. Rotated files are renamed with the time of the
+  ## rotation, and the log file is reopened on SIGUSR1 for external tools.
+  # logfile_rotation_interval = "24h"
+  # logfile_rotation_max_size = 10485760
+  ## Remove the rotated log files older than this duration, and the oldest
+  ## ones in excess of this number of files.
+  # logfile_rotation_max_age = "168h"
+  # logfile_rotation_max_archives = 5
+  ## Format of the log messages, "text" or "json" to write each message as a
+  ## JSON object.
+  log_format = "text"
+  ## Layout of the log timestamps, in the format of Go's time package.
+  # log_timestamp_format = "2006-01-02T15:04:05Z07:00"
 
   ## Override default hostname, if empty use os.Hostname()
   hostname = ""
   ## If set to true, do no set the "host" tag in the telegraf agent.
   omit_hostname = false
 
+  ## Address of the HTTP server reporting the health of telegraf on /health
+  ## and /ready, eg. ":8080". The server is disabled when empty.
+  health_listen = ""
+  ## The thresholds making /health report telegraf as unhealthy, disabled
+  ## when zero:
+  ##   health_max_write_age:   an output did not write successfully for longer.
+  ##   health_max_buffer_fill: the buffer of an output is more than this ratio
+  ##                           full, between 0 and 1.
+  ##   health_max_error_age:   an input reported a gather error more recently.
+  # health_max_write_age = "5m"
+  # health_max_buffer_fill = 0.9
+  # health_max_error_age = "1m"
+
 
 ###############################################################################
 #                            OUTPUT PLUGINS                                   #
@@ -541,6 +644,10 @@ func getDefaultConfigPath() (string, error) {
 	if runtime.GOOS == "windows" {
 		etcfile = `C:\Program Files\Telegraf\telegraf.conf`
 	}
+	if isURL(envfile) {
+		log.Printf("I! Using config URL: %s", envfile)
+		return envfile, nil
+	}
 	for _, path := range []string{envfile, homefile, etcfile} {
 		if _, err := os.Stat(path); err == nil {
 			log.Printf("I! Using config file: %s", path)
@@ -561,7 +668,7 @@ func (c *Config) LoadConfig(path string) error {
 			return err
 		}
 	}
-	tbl, err := parseFile(path)
+	tbl, err := c.parseFile(path)
 	if err != nil {
 		return fmt.Errorf("Error parsing %s, %s", path, err)
 	}
@@ -590,6 +697,26 @@ func (c *Config) LoadConfig(path string) error {
 			log.Printf("E! Could not parse [agent] config\n")
 			return fmt.Errorf("Error parsing %s, %s", path, err)
 		}
+		switch c.Agent.LogFormat {
+		case "", "text", "json":
+		default:
+			return fmt.Errorf("Error parsing %s, invalid log_format %q, "+
+				"must be text or json", path, c.Agent.LogFormat)
+		}
+	}
+
+	// Parse the routing table:
+	if val, ok := tbl.Fields["router"]; ok {
+		subTables, ok := val.([]*ast.Table)
+		if !ok {
+			return fmt.Errorf("%s: invalid configuration, use [[router]]", path)
+		}
+		for _, t := range subTables {
+			if err = c.addRoute(t); err != nil {
+				return fmt.Errorf("Error parsing %s, %s", path, err)
+			}
+		}
+		delete(tbl.Fields, "router")
 	}
 
 	// Parse all the rest of the plugins:
@@ -682,6 +809,59 @@ func (c *Config) LoadConfig(path string) error {
 	return nil
 }
 
+// checksum returns a hash of a plugin table, used to tell whether the
+// configuration of a plugin changed when the config is reloaded. It must be
+// computed before the options are removed from the table while building the
+// plugin.
+func checksum(tbl *ast.Table) uint64 {
+	h := fnv.New64a()
+	writeTable(h, tbl)
+	return h.Sum64()
+}
+
+// writeTable writes the options of tbl to w, sorted by name.
+func writeTable(w io.Writer, tbl *ast.Table) {
+	keys := make([]string, 0, len(tbl.Fields))
+	for key := range tbl.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		switch v := tbl.Fields[key].(type) {
+		case *ast.KeyValue:
+			fmt.Fprintf(w, "%s=%s\n", key, valueSource(v.Value))
+		case *ast.Table:
+			fmt.Fprintf(w, "[%s]\n", key)
+			writeTable(w, v)
+			fmt.Fprintf(w, "[/%s]\n", key)
+		case []*ast.Table:
+			for _, t := range v {
+				fmt.Fprintf(w, "[[%s]]\n", key)
+				writeTable(w, t)
+				fmt.Fprintf(w, "[[/%s]]\n", key)
+			}
+		}
+	}
+}
+
+// valueSource returns the TOML source of val, with the string values
+// replaced by their resolved secrets so that changed secrets change the
+// checksum.
+func valueSource(val ast.Value) string {
+	switch v := val.(type) {
+	case *ast.String:
+		return strconv.Quote(v.Value)
+	case *ast.Array:
+		elems := make([]string, 0, len(v.Value))
+		for _, elem := range v.Value {
+			elems = append(elems, valueSource(elem))
+		}
+		return "[" + strings.Join(elems, ",") + "]"
+	}
+	return val.Source()
+}
+
 // trimBOM trims the Byte-Order-Marks from the beginning of the file.
 // this is for Windows compatability only.
 // see https://github.com/influxdata/telegraf/issues/1378
@@ -691,12 +871,34 @@ func trimBOM(f []byte) []byte {
 
 // parseFile loads a TOML configuration from a provided path and
 // returns the AST produced from the TOML parser. When loading the file, it
-// will find environment variables and replace them.
-func parseFile(fpath string) (*ast.Table, error) {
-	contents, err := ioutil.ReadFile(fpath)
+// will find environment variables and replace them, and resolve the secret
+// references of the string values. Paths starting with
+// http:// or https:// are fetched with the settings of c.Remote.
+func (c *Config) parseFile(fpath string) (*ast.Table, error) {
+	var contents []byte
+	var err error
+	if isURL(fpath) {
+		contents, err = c.fetchRemote(fpath)
+	} else {
+		contents, err = ioutil.ReadFile(fpath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tbl, err := parseConfig(contents)
 	if err != nil {
 		return nil, err
 	}
+	if err := resolveSecrets(tbl); err != nil {
+		return nil, err
+	}
+	return tbl, nil
+}
+
+// parseConfig returns the AST of the TOML configuration, with the
+// environment variables replaced.
+func parseConfig(contents []byte) (*ast.Table, error) {
 	// ugh windows why
 	contents = trimBOM(contents)
 
@@ -747,10 +949,9 @@ func (c *Config) addProcessor(name string, table *ast.Table) error {
 		return err
 	}
 
-	rf := &models.RunningProcessor{
-		Name:      name,
-		Processor: processor,
-		Config:    processorConfig,
+	rf := models.NewRunningProcessor(name, processor, processorConfig)
+	if err := rf.Init(); err != nil {
+		return fmt.Errorf("processors.%s: %s", rf.LogName(), err)
 	}
 
 	c.Processors = append(c.Processors, rf)
@@ -766,6 +967,7 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested output: %s", name)
 	}
 	output := creator()
+	sum := checksum(table)
 
 	// If the output has a SetSerializer function, then this means it can write
 	// arbitrary types of output, so build the serializer and set it.
@@ -789,10 +991,20 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 
 	ro := models.NewRunningOutput(name, output, outputConfig,
 		c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
+	ro.Checksum = sum
 	c.Outputs = append(c.Outputs, ro)
 	return nil
 }
 
+func (c *Config) addRoute(table *ast.Table) error {
+	rc, err := buildRoute(table)
+	if err != nil {
+		return err
+	}
+	c.Routes = append(c.Routes, rc)
+	return nil
+}
+
 func (c *Config) addInput(name string, table *ast.Table) error {
 	if len(c.InputFilters) > 0 && !sliceContains(name, c.InputFilters) {
 		return nil
@@ -807,6 +1019,7 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested input: %s", name)
 	}
 	input := creator()
+	sum := checksum(table)
 
 	// If the input has a SetParser function, then this means it can accept
 	// arbitrary types of input, so build the parser and set it.
@@ -829,6 +1042,7 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	}
 
 	rp := models.NewRunningInput(input, pluginConfig)
+	rp.Checksum = sum
 	c.Inputs = append(c.Inputs, rp)
 	return nil
 }
@@ -851,6 +1065,18 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 		Period: time.Second * 30,
 	}
 
+	if node, ok := tbl.Fields["alias"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				conf.Alias = str.Value
+			}
+		}
+	}
+
+	if err := buildLogLevel(tbl, &conf.LogLevel); err != nil {
+		return nil, err
+	}
+
 	if node, ok := tbl.Fields["period"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -922,6 +1148,7 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 		}
 	}
 
+	delete(tbl.Fields, "alias")
 	delete(tbl.Fields, "period")
 	delete(tbl.Fields, "delay")
 	delete(tbl.Fields, "drop_original")
@@ -950,6 +1177,18 @@ func buildProcessor(name string, tbl *ast.Table) (*models.ProcessorConfig, error
 		}
 	}
 
+	if node, ok := tbl.Fields["alias"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				conf.Alias = str.Value
+			}
+		}
+	}
+
+	if err := buildLogLevel(tbl, &conf.LogLevel); err != nil {
+		return nil, err
+	}
+
 	if node, ok := tbl.Fields["order"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if b, ok := kv.Value.(*ast.Integer); ok {
@@ -962,6 +1201,7 @@ func buildProcessor(name string, tbl *ast.Table) (*models.ProcessorConfig, error
 		}
 	}
 
+	delete(tbl.Fields, "alias")
 	delete(tbl.Fields, "order")
 	var err error
 	conf.Filter, err = buildFilter(tbl)
@@ -971,6 +1211,90 @@ func buildProcessor(name string, tbl *ast.Table) (*models.ProcessorConfig, error
 	return conf, nil
 }
 
+// buildSeriesLimit sets limit to the max_series options of an input, and
+// removes the options from tbl.
+func buildSeriesLimit(tbl *ast.Table, limit *models.SeriesLimitConfig) error {
+	if node, ok := tbl.Fields["max_series"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				var err error
+				limit.MaxSeries, err = strconv.Atoi(integer.Value)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["max_series_window"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return err
+				}
+
+				limit.Window = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["max_series_action"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				limit.Action = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["max_series_rewrite_tags"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						limit.RewriteTags = append(limit.RewriteTags, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	switch limit.Action {
+	case "", models.SeriesActionDrop:
+	case models.SeriesActionRewrite:
+		if len(limit.RewriteTags) == 0 {
+			return fmt.Errorf("max_series_action %q requires max_series_rewrite_tags",
+				limit.Action)
+		}
+	default:
+		return fmt.Errorf("invalid max_series_action %q, must be %q or %q",
+			limit.Action, models.SeriesActionDrop, models.SeriesActionRewrite)
+	}
+
+	delete(tbl.Fields, "max_series")
+	delete(tbl.Fields, "max_series_window")
+	delete(tbl.Fields, "max_series_action")
+	delete(tbl.Fields, "max_series_rewrite_tags")
+	return nil
+}
+
+// buildLogLevel sets level to the log_level option of a plugin, and removes
+// the option from tbl.
+func buildLogLevel(tbl *ast.Table, level *string) error {
+	if node, ok := tbl.Fields["log_level"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				if _, err := logger.ParseLevel(str.Value); err != nil {
+					return err
+				}
+				*level = str.Value
+			}
+		}
+	}
+	delete(tbl.Fields, "log_level")
+	return nil
+}
+
 // buildFilter builds a Filter
 // (tagpass/tagdrop/namepass/namedrop/fieldpass/fielddrop) to
 // be inserted into the models.OutputConfig/models.InputConfig
@@ -1108,11 +1432,58 @@ func buildFilter(tbl *ast.Table) (models.Filter, error) {
 	return f, nil
 }
 
+// buildRoute parses a [[router]] table. The metrics passing the namepass,
+// namedrop, fieldpass, fielddrop, tagpass and tagdrop filters are sent to the
+// outputs listed in outputs.
+func buildRoute(tbl *ast.Table) (*models.RouteConfig, error) {
+	filter, err := buildFilter(tbl)
+	if err != nil {
+		return nil, err
+	}
+	if len(filter.TagInclude) > 0 || len(filter.TagExclude) > 0 {
+		return nil, fmt.Errorf("router does not support taginclude and tagexclude")
+	}
+	rc := &models.RouteConfig{Filter: filter}
+
+	if node, ok := tbl.Fields["outputs"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						rc.Outputs = append(rc.Outputs, str.Value)
+					}
+				}
+			}
+		}
+	}
+	delete(tbl.Fields, "outputs")
+
+	if len(rc.Outputs) == 0 {
+		return nil, fmt.Errorf("router has no outputs")
+	}
+	for name := range tbl.Fields {
+		return nil, fmt.Errorf("router has unknown option %s", name)
+	}
+	return rc, nil
+}
+
 // buildInput parses input specific items from the ast.Table,
 // builds the filter and returns a
 // models.InputConfig to be inserted into models.RunningInput
 func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 	cp := &models.InputConfig{Name: name}
+	if node, ok := tbl.Fields["alias"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				cp.Alias = str.Value
+			}
+		}
+	}
+
+	if err := buildLogLevel(tbl, &cp.LogLevel); err != nil {
+		return nil, err
+	}
+
 	if node, ok := tbl.Fields["interval"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -1126,6 +1497,74 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["gather_timeout"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				cp.GatherTimeout = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["collection_jitter"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				cp.CollectionJitter = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["collection_offset"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				cp.CollectionOffset = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["precision"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				cp.Precision = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["align_timestamps"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				var err error
+				cp.AlignTimestamps, err = b.Boolean()
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if err := buildSeriesLimit(tbl, &cp.SeriesLimit); err != nil {
+		return nil, err
+	}
+
 	if node, ok := tbl.Fields["name_prefix"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -1161,8 +1600,14 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 
 	delete(tbl.Fields, "name_prefix")
 	delete(tbl.Fields, "name_suffix")
+	delete(tbl.Fields, "alias")
 	delete(tbl.Fields, "name_override")
 	delete(tbl.Fields, "interval")
+	delete(tbl.Fields, "gather_timeout")
+	delete(tbl.Fields, "collection_jitter")
+	delete(tbl.Fields, "collection_offset")
+	delete(tbl.Fields, "precision")
+	delete(tbl.Fields, "align_timestamps")
 	delete(tbl.Fields, "tags")
 	var err error
 	cp.Filter, err = buildFilter(tbl)
@@ -1342,9 +1787,142 @@ func buildOutput(name string, tbl *ast.Table) (*models.OutputConfig, error) {
 		return nil, err
 	}
 	oc := &models.OutputConfig{
-		Name:   name,
-		Filter: filter,
+		Name:          name,
+		Filter:        filter,
+		FlushWhenFull: true,
+	}
+
+	if node, ok := tbl.Fields["alias"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.Alias = str.Value
+			}
+		}
+	}
+
+	if err := buildLogLevel(tbl, &oc.LogLevel); err != nil {
+		return nil, err
+	}
+
+	if node, ok := tbl.Fields["flush_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.FlushInterval, err = time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["flush_jitter"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.FlushJitter, err = time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["flush_when_full"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				oc.FlushWhenFull, err = strconv.ParseBool(b.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["buffer_directory"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.BufferDirectory = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["buffer_max_size"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				oc.BufferMaxSize, err = strconv.ParseInt(integer.Value, 10, 64)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_max_attempts"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				oc.Retry.MaxAttempts, err = strconv.Atoi(integer.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
 	}
+
+	if node, ok := tbl.Fields["retry_initial_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.Retry.InitialInterval, err = time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_max_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.Retry.MaxInterval, err = time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["circuit_breaker_threshold"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				oc.Retry.BreakerThreshold, err = strconv.Atoi(integer.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["circuit_breaker_timeout"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.Retry.BreakerTimeout, err = time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	delete(tbl.Fields, "alias")
+	delete(tbl.Fields, "flush_interval")
+	delete(tbl.Fields, "flush_jitter")
+	delete(tbl.Fields, "flush_when_full")
+	delete(tbl.Fields, "buffer_directory")
+	delete(tbl.Fields, "buffer_max_size")
+	delete(tbl.Fields, "retry_max_attempts")
+	delete(tbl.Fields, "retry_initial_interval")
+	delete(tbl.Fields, "retry_max_interval")
+	delete(tbl.Fields, "circuit_breaker_threshold")
+	delete(tbl.Fields, "circuit_breaker_timeout")
+
 	// Outputs don't support FieldDrop/FieldPass, so set to NameDrop/NamePass
 	if len(oc.Filter.FieldDrop) > 0 {
 		oc.Filter.NameDrop = oc.Filter.FieldDrop
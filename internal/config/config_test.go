@@ -12,7 +12,10 @@ import (
 	"github.com/influxdata/telegraf/plugins/inputs/procstat"
 	"github.com/influxdata/telegraf/plugins/parsers"
 
+	"github.com/influxdata/toml"
+	"github.com/influxdata/toml/ast"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConfig_LoadSingleInputWithEnvVars(t *testing.T) {
@@ -174,3 +177,192 @@ func TestConfig_LoadDirectory(t *testing.T) {
 	assert.Equal(t, pConfig, c.Inputs[3].Config,
 		"Merged Testdata did not produce correct procstat metadata.")
 }
+
+func TestConfig_BuildOutput(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`
+flush_interval = "30s"
+flush_jitter = "5s"
+flush_when_full = false
+buffer_directory = "/var/lib/telegraf/influxdb"
+buffer_max_size = 1048576
+retry_max_attempts = 5
+retry_initial_interval = "1s"
+retry_max_interval = "30s"
+circuit_breaker_threshold = 10
+circuit_breaker_timeout = "1m"
+namepass = ["cpu"]
+urls = ["http://localhost:8086"]
+`))
+	require.NoError(t, err)
+
+	oc, err := buildOutput("influxdb", tbl)
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, oc.FlushInterval)
+	assert.Equal(t, 5*time.Second, oc.FlushJitter)
+	assert.False(t, oc.FlushWhenFull)
+	assert.Equal(t, "/var/lib/telegraf/influxdb", oc.BufferDirectory)
+	assert.Equal(t, int64(1048576), oc.BufferMaxSize)
+	assert.Equal(t, models.RetryConfig{
+		MaxAttempts:      5,
+		InitialInterval:  time.Second,
+		MaxInterval:      30 * time.Second,
+		BreakerThreshold: 10,
+		BreakerTimeout:   time.Minute,
+	}, oc.Retry)
+	assert.Equal(t, []string{"cpu"}, oc.Filter.NamePass)
+
+	// only the plugin's own options are left for the plugin
+	assert.Len(t, tbl.Fields, 1)
+	assert.IsType(t, &ast.KeyValue{}, tbl.Fields["urls"])
+}
+
+func TestConfig_BuildOutputDefaults(t *testing.T) {
+	tbl, err := toml.Parse([]byte(``))
+	require.NoError(t, err)
+
+	oc, err := buildOutput("influxdb", tbl)
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), oc.FlushInterval)
+	assert.True(t, oc.FlushWhenFull)
+	assert.Equal(t, "", oc.BufferDirectory)
+}
+
+func TestConfig_BuildRoute(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`
+namepass = ["cpu*"]
+outputs = ["primary", "file"]
+[tagpass]
+  host = ["web*"]
+`))
+	require.NoError(t, err)
+
+	rc, err := buildRoute(tbl)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"primary", "file"}, rc.Outputs)
+	assert.Equal(t, []string{"cpu*"}, rc.Filter.NamePass)
+	require.Len(t, rc.Filter.TagPass, 1)
+	assert.Equal(t, "host", rc.Filter.TagPass[0].Name)
+	assert.True(t, rc.Filter.IsActive())
+}
+
+func TestConfig_BuildRouteErrors(t *testing.T) {
+	for _, cfg := range []string{
+		`namepass = ["cpu"]`,
+		`outputs = ["file"]
+namepas = ["cpu"]`,
+		`outputs = ["file"]
+taginclude = ["host"]`,
+	} {
+		tbl, err := toml.Parse([]byte(cfg))
+		require.NoError(t, err)
+		_, err = buildRoute(tbl)
+		assert.Error(t, err, cfg)
+	}
+}
+
+func TestConfig_LoadRouter(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/router.toml"))
+	require.Len(t, c.Routes, 2)
+	assert.Equal(t, []string{"primary"}, c.Routes[0].Outputs)
+	assert.Equal(t, []string{"archive"}, c.Routes[1].Outputs)
+}
+
+func TestConfig_BuildAlias(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`alias = "frontend"`))
+	require.NoError(t, err)
+	ic, err := buildInput("http_response", tbl)
+	require.NoError(t, err)
+	assert.Equal(t, "frontend", ic.Alias)
+	assert.Len(t, tbl.Fields, 0)
+
+	tbl, err = toml.Parse([]byte(`alias = "rename_hosts"`))
+	require.NoError(t, err)
+	pc, err := buildProcessor("rename", tbl)
+	require.NoError(t, err)
+	assert.Equal(t, "rename_hosts", pc.Alias)
+	assert.Len(t, tbl.Fields, 0)
+
+	tbl, err = toml.Parse([]byte(`alias = "cpu_minmax"`))
+	require.NoError(t, err)
+	ac, err := buildAggregator("minmax", tbl)
+	require.NoError(t, err)
+	assert.Equal(t, "cpu_minmax", ac.Alias)
+	assert.Len(t, tbl.Fields, 0)
+}
+
+func TestConfig_BuildInputSchedule(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`
+collection_jitter = "2s"
+collection_offset = "5s"
+gather_timeout = "30s"
+`))
+	require.NoError(t, err)
+	ic, err := buildInput("mysql", tbl)
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, ic.GatherTimeout)
+	assert.Equal(t, 2*time.Second, ic.CollectionJitter)
+	assert.Equal(t, 5*time.Second, ic.CollectionOffset)
+	assert.Empty(t, tbl.Fields)
+}
+
+func TestConfig_BuildInputPrecision(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`
+precision = "1ms"
+align_timestamps = true
+`))
+	require.NoError(t, err)
+	ic, err := buildInput("cpu", tbl)
+	require.NoError(t, err)
+	assert.Equal(t, time.Millisecond, ic.Precision)
+	assert.True(t, ic.AlignTimestamps)
+	assert.Empty(t, tbl.Fields)
+
+	tbl, err = toml.Parse([]byte(`precision = "fast"`))
+	require.NoError(t, err)
+	_, err = buildInput("cpu", tbl)
+	assert.Error(t, err)
+}
+
+func TestConfig_BuildInputSeriesLimit(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`
+max_series = 1000
+max_series_window = "10m"
+max_series_action = "rewrite"
+max_series_rewrite_tags = ["request_id"]
+`))
+	require.NoError(t, err)
+	ic, err := buildInput("http_listener", tbl)
+	require.NoError(t, err)
+	assert.Equal(t, models.SeriesLimitConfig{
+		MaxSeries:   1000,
+		Window:      10 * time.Minute,
+		Action:      "rewrite",
+		RewriteTags: []string{"request_id"},
+	}, ic.SeriesLimit)
+	assert.Empty(t, tbl.Fields)
+
+	tbl, err = toml.Parse([]byte(`max_series_action = "rewrite"`))
+	require.NoError(t, err)
+	_, err = buildInput("http_listener", tbl)
+	assert.Error(t, err)
+
+	tbl, err = toml.Parse([]byte(`max_series_action = "sample"`))
+	require.NoError(t, err)
+	_, err = buildInput("http_listener", tbl)
+	assert.Error(t, err)
+}
+
+func TestConfig_BuildLogLevel(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`log_level = "debug"`))
+	require.NoError(t, err)
+	ic, err := buildInput("cpu", tbl)
+	require.NoError(t, err)
+	assert.Equal(t, "debug", ic.LogLevel)
+	assert.Empty(t, tbl.Fields)
+
+	tbl, err = toml.Parse([]byte(`log_level = "verbose"`))
+	require.NoError(t, err)
+	_, err = buildOutput("file", tbl)
+	assert.Error(t, err)
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+// Default timeout of the requests fetching remote config files.
+const DefaultRemoteTimeout = 10 * time.Second
+
+// RemoteConfig contains the settings used to load config files from
+// http:// and https:// URLs.
+type RemoteConfig struct {
+	// Token, when set, is sent as a bearer token in the Authorization header.
+	Token string
+
+	// Path to CA file
+	SSLCA string
+	// Path to host cert file
+	SSLCert string
+	// Path to cert key file
+	SSLKey string
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool
+
+	Timeout time.Duration
+
+	// client is shared by all the requests, so that its connections are
+	// reused between the polls.
+	client     *http.Client
+	clientErr  error
+	clientOnce sync.Once
+}
+
+// remoteFile is a config file that was loaded from a URL.
+type remoteFile struct {
+	url  string
+	etag string
+	sum  uint64
+}
+
+func isURL(path string) bool {
+	return strings.HasPrefix(path, "http://") ||
+		strings.HasPrefix(path, "https://")
+}
+
+// fetchRemote returns the config file at url, and remembers it so that
+// WatchRemote can tell when it changes.
+func (c *Config) fetchRemote(url string) ([]byte, error) {
+	body, etag, err := c.Remote.get(url, "")
+	if err != nil {
+		return nil, err
+	}
+	c.remotes = append(c.remotes, &remoteFile{
+		url:  url,
+		etag: etag,
+		sum:  contentSum(body),
+	})
+	return body, nil
+}
+
+// WatchRemote polls the config files loaded from URLs every interval, until
+// stop is closed. The returned channel is closed when one of them has
+// changed, so that the config can be reloaded. It is never closed if no file
+// was loaded from a URL or interval is 0.
+func (c *Config) WatchRemote(interval time.Duration, stop <-chan struct{}) <-chan struct{} {
+	changed := make(chan struct{})
+	if len(c.remotes) == 0 || interval <= 0 {
+		return changed
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		defer c.Remote.closeIdleConnections()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			for _, f := range c.remotes {
+				ok, err := c.Remote.changed(f)
+				if err != nil {
+					log.Printf("E! Error polling config %s: %s", f.url, err)
+					continue
+				}
+				if ok {
+					log.Printf("I! Config %s has changed", f.url)
+					close(changed)
+					return
+				}
+			}
+		}
+	}()
+	return changed
+}
+
+// changed returns true if the content of the remote file has changed since
+// it was loaded. The ETag of the file is sent, so that the server does not
+// send the file again when it did not change.
+func (r *RemoteConfig) changed(f *remoteFile) (bool, error) {
+	body, etag, err := r.get(f.url, f.etag)
+	if err != nil {
+		return false, err
+	}
+	if body == nil {
+		// not modified
+		return false, nil
+	}
+	f.etag = etag
+	return contentSum(body) != f.sum, nil
+}
+
+// get fetches the file at url. When etag is set and the server replies that
+// the file did not change, get returns a nil body.
+func (r *RemoteConfig) get(url, etag string) ([]byte, string, error) {
+	client, err := r.httpClient()
+	if err != nil {
+		return nil, "", err
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if r.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.Token)
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, etag, nil
+	default:
+		return nil, "", fmt.Errorf("%s returned HTTP status %s", url, resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, resp.Header.Get("ETag"), nil
+}
+
+// httpClient returns the client of the requests, created on first use.
+func (r *RemoteConfig) httpClient() (*http.Client, error) {
+	r.clientOnce.Do(func() {
+		tlsCfg, err := internal.GetTLSConfig(
+			r.SSLCert, r.SSLKey, r.SSLCA, r.InsecureSkipVerify)
+		if err != nil {
+			r.clientErr = err
+			return
+		}
+		timeout := r.Timeout
+		if timeout == 0 {
+			timeout = DefaultRemoteTimeout
+		}
+		r.client = &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsCfg,
+			},
+			Timeout: timeout,
+		}
+	})
+	return r.client, r.clientErr
+}
+
+// closeIdleConnections closes the connections kept open by the client once
+// the files are not polled anymore.
+func (r *RemoteConfig) closeIdleConnections() {
+	if r.client == nil {
+		return
+	}
+	if t, ok := r.client.Transport.(*http.Transport); ok {
+		t.CloseIdleConnections()
+	}
+}
+
+func contentSum(b []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(b)
+	return h.Sum64()
+}
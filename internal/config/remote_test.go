@@ -0,0 +1,140 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// configServer serves a config file with an ETag, and requires a bearer
+// token.
+type configServer struct {
+	sync.Mutex
+	config   string
+	version  int
+	requests int
+}
+
+func (s *configServer) set(config string) {
+	s.Lock()
+	defer s.Unlock()
+	s.config = config
+	s.version++
+}
+
+func (s *configServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	s.requests++
+
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	etag := fmt.Sprintf(`"%d"`, s.version)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	fmt.Fprint(w, s.config)
+}
+
+const remoteConfig = `
+[[inputs.memcached]]
+  servers = ["localhost"]
+`
+
+func TestConfig_LoadRemoteConfig(t *testing.T) {
+	s := &configServer{}
+	s.set(remoteConfig)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c := NewConfig()
+	c.Remote.Token = "secret"
+	require.NoError(t, c.LoadConfig(ts.URL+"/telegraf.conf"))
+	require.Len(t, c.Inputs, 1)
+	assert.Equal(t, "memcached", c.Inputs[0].Config.Name)
+
+	c = NewConfig()
+	err := c.LoadConfig(ts.URL + "/telegraf.conf")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+}
+
+func TestConfig_WatchRemote(t *testing.T) {
+	s := &configServer{}
+	s.set(remoteConfig)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c := NewConfig()
+	c.Remote.Token = "secret"
+	require.NoError(t, c.LoadConfig(ts.URL))
+
+	stop := make(chan struct{})
+	defer close(stop)
+	changed := c.WatchRemote(10*time.Millisecond, stop)
+
+	// the server replies 304 while the config does not change
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-changed:
+		t.Fatal("config reported as changed")
+	default:
+	}
+
+	s.set(remoteConfig + "  timeout = \"5s\"\n")
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("config change not detected")
+	}
+}
+
+func TestConfig_WatchRemoteWithoutRemoteConfig(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/single_plugin.toml"))
+
+	stop := make(chan struct{})
+	defer close(stop)
+	select {
+	case <-c.WatchRemote(time.Millisecond, stop):
+		t.Fatal("local config reported as changed")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// Verify that the polls reuse the connection to the server.
+func TestConfig_WatchRemoteReusesConnection(t *testing.T) {
+	s := &configServer{}
+	s.set(remoteConfig)
+	var conns int32
+	ts := httptest.NewUnstartedServer(s)
+	ts.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	c := NewConfig()
+	c.Remote.Token = "secret"
+	require.NoError(t, c.LoadConfig(ts.URL))
+
+	stop := make(chan struct{})
+	defer close(stop)
+	c.WatchRemote(5*time.Millisecond, stop)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&conns))
+}
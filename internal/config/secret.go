@@ -0,0 +1,181 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+
+	"github.com/influxdata/toml/ast"
+	"github.com/kballard/go-shellquote"
+)
+
+// secretExecTimeout is the time given to the command of an exec secret.
+const secretExecTimeout = 10 * time.Second
+
+// secretRe matches the secret references, eg. @{env:INFLUX_PASSWORD}.
+var secretRe = regexp.MustCompile(`@\{(\w+):([^}]*)\}`)
+
+// SecretProvider returns the secret for a key. A reference @{file:/path} is
+// resolved by the "file" provider with the key "/path".
+type SecretProvider func(key string) (string, error)
+
+var secretProviders = map[string]SecretProvider{
+	"file": fileSecret,
+	"env":  envSecret,
+	"exec": execSecret,
+}
+
+// AddSecretProvider registers the provider resolving the @{name:key}
+// references of the configuration.
+func AddSecretProvider(name string, provider SecretProvider) {
+	secretProviders[name] = provider
+}
+
+// fileSecret returns the content of the file, without its trailing newline.
+func fileSecret(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// envSecret returns the value of the environment variable.
+func envSecret(name string) (string, error) {
+	val, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return val, nil
+}
+
+// execSecret returns the output of the command, without its trailing
+// newline.
+func execSecret(command string) (string, error) {
+	args, err := splitCommand(command)
+	if err != nil {
+		return "", err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := internal.RunTimeout(cmd, secretExecTimeout); err != nil {
+		return "", fmt.Errorf("running %s: %s: %s", args[0], err,
+			strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
+
+// splitCommand returns the arguments of the command of an exec secret.
+func splitCommand(command string) ([]string, error) {
+	args, err := shellquote.Split(command)
+	if err != nil || len(args) == 0 {
+		return nil, fmt.Errorf("unable to parse command %q", command)
+	}
+	return args, nil
+}
+
+// resolveSecrets replaces the secret references in the string options of
+// tbl and of its sub-tables. The secrets are registered to be redacted from
+// the logs.
+func resolveSecrets(tbl *ast.Table) error {
+	return walkStrings(tbl, func(line int, s *ast.String) error {
+		resolved, err := resolveString(s.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: %s", line, err)
+		}
+		s.Value = resolved
+		return nil
+	})
+}
+
+// walkStrings calls fn with the string values of the options of tbl and of
+// its sub-tables, and the line of their option, until it returns an error.
+func walkStrings(tbl *ast.Table, fn func(line int, s *ast.String) error) error {
+	for _, val := range tbl.Fields {
+		switch v := val.(type) {
+		case *ast.KeyValue:
+			if err := walkValue(v.Line, v.Value, fn); err != nil {
+				return err
+			}
+		case *ast.Table:
+			if err := walkStrings(v, fn); err != nil {
+				return err
+			}
+		case []*ast.Table:
+			for _, t := range v {
+				if err := walkStrings(t, fn); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func walkValue(line int, val ast.Value, fn func(line int, s *ast.String) error) error {
+	switch v := val.(type) {
+	case *ast.String:
+		return fn(line, v)
+	case *ast.Array:
+		for _, elem := range v.Value {
+			if err := walkValue(line, elem, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveString replaces the secret references in s.
+func resolveString(s string) (string, error) {
+	if !strings.Contains(s, "@{") {
+		return s, nil
+	}
+
+	var err error
+	resolved := secretRe.ReplaceAllStringFunc(s, func(ref string) string {
+		if err != nil {
+			return ref
+		}
+		match := secretRe.FindStringSubmatch(ref)
+		provider, ok := secretProviders[match[1]]
+		if !ok {
+			err = fmt.Errorf("unknown secret provider %q", match[1])
+			return ref
+		}
+		secret, perr := provider(match[2])
+		if perr != nil {
+			err = fmt.Errorf("resolving secret %s: %s", ref, perr)
+			return ref
+		}
+		internal.AddSecret(secret)
+		return secret
+	})
+	return resolved, err
+}
+
+// checkString returns an error if a secret reference in s has an unknown
+// provider, or an exec command that cannot be parsed.
+func checkString(s string) error {
+	for _, match := range secretRe.FindAllStringSubmatch(s, -1) {
+		if _, ok := secretProviders[match[1]]; !ok {
+			return fmt.Errorf("unknown secret provider %q", match[1])
+		}
+		if match[1] == "exec" {
+			if _, err := splitCommand(match[2]); err != nil {
+				return fmt.Errorf("secret %s: %s", match[0], err)
+			}
+		}
+	}
+	return nil
+}
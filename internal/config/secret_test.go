@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs/exec"
+	"github.com/influxdata/telegraf/plugins/inputs/memcached"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_LoadSecrets(t *testing.T) {
+	require.NoError(t, os.Setenv("MY_TEST_SERVER", "192.168.1.1"))
+
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/secrets.toml"))
+	require.Len(t, c.Inputs, 2)
+
+	for _, input := range c.Inputs {
+		switch p := input.Input.(type) {
+		case *memcached.Memcached:
+			assert.Equal(t, []string{"192.168.1.1"}, p.Servers)
+		case *exec.Exec:
+			assert.Equal(t, []string{"cat /tmp/secret"}, p.Commands)
+		default:
+			t.Errorf("unexpected input %s", input.Name())
+		}
+	}
+
+	assert.Equal(t, "server ****", internal.Redact("server 192.168.1.1"))
+}
+
+func TestResolveString(t *testing.T) {
+	require.NoError(t, os.Setenv("SECRET_TEST_USER", "telegraf"))
+	AddSecretProvider("test", func(key string) (string, error) {
+		if key == "missing" {
+			return "", fmt.Errorf("no such secret")
+		}
+		return "value-of-" + key, nil
+	})
+
+	tests := []struct {
+		in  string
+		out string
+		err bool
+	}{
+		{in: "no secret", out: "no secret"},
+		{in: "@{env:SECRET_TEST_USER}", out: "telegraf"},
+		{in: "user=@{env:SECRET_TEST_USER} password=@{test:pw}",
+			out: "user=telegraf password=value-of-pw"},
+		{in: "@{exec:echo 'from exec'}", out: "from exec"},
+		{in: "@{file:./testdata/secret.txt}", out: "cat /tmp/secret"},
+		{in: "@{env:SECRET_TEST_UNSET_VARIABLE}", err: true},
+		{in: "@{file:./testdata/does_not_exist}", err: true},
+		{in: "@{exec:false}", err: true},
+		{in: "@{test:missing}", err: true},
+		{in: "@{vault:secret/telegraf}", err: true},
+	}
+	for _, tt := range tests {
+		out, err := resolveString(tt.in)
+		if tt.err {
+			assert.Error(t, err, tt.in)
+			continue
+		}
+		assert.NoError(t, err, tt.in)
+		assert.Equal(t, tt.out, out)
+	}
+}
+
+func TestChecksumChangesWithSecret(t *testing.T) {
+	require.NoError(t, os.Setenv("MY_TEST_SERVER", "192.168.1.1"))
+	c := NewConfig()
+	tbl, err := c.parseFile("./testdata/secrets.toml")
+	require.NoError(t, err)
+	first := checksum(tbl)
+
+	require.NoError(t, os.Setenv("MY_TEST_SERVER", "10.0.0.1"))
+	tbl, err = c.parseFile("./testdata/secrets.toml")
+	require.NoError(t, err)
+	assert.NotEqual(t, first, checksum(tbl))
+}
@@ -154,3 +154,12 @@ func TestDuration(t *testing.T) {
 	d.UnmarshalTOML([]byte(`1.5`))
 	assert.Equal(t, time.Second, d.Duration)
 }
+
+func TestRedact(t *testing.T) {
+	AddSecret("hunter2")
+	AddSecret("abc")
+
+	assert.Equal(t, "password=****, user=abc",
+		Redact("password=hunter2, user=abc"))
+	assert.Equal(t, "nothing to hide", Redact("nothing to hide"))
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"reflect"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/logger"
+)
+
+// logName returns the name identifying a plugin instance in log messages,
+// name::alias when the instance has an alias.
+func logName(name, alias string) string {
+	if alias == "" {
+		return name
+	}
+	return name + "::" + alias
+}
+
+// newLogger returns the logger of a plugin instance, and gives it to the
+// plugin when the plugin has a Log field.
+func newLogger(plugin interface{}, name, alias, level string) telegraf.Logger {
+	l := logger.New(name, alias, level)
+	setLogger(plugin, l)
+	return l
+}
+
+// setLogger sets the exported field Log of type telegraf.Logger of plugin,
+// if plugin is a pointer to a struct with such a field.
+func setLogger(plugin interface{}, l telegraf.Logger) {
+	v := reflect.ValueOf(plugin)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return
+	}
+	f := v.Elem().FieldByName("Log")
+	if !f.IsValid() || !f.CanSet() ||
+		f.Type() != reflect.TypeOf((*telegraf.Logger)(nil)).Elem() {
+		return
+	}
+	f.Set(reflect.ValueOf(l))
+}
+
+// statTags returns the selfstat tags identifying a plugin instance. key is
+// the plugin type, eg. "input", and the alias tag is only set for instances
+// that have one.
+func statTags(key, name, alias string) map[string]string {
+	tags := map[string]string{key: name}
+	if alias != "" {
+		tags["alias"] = alias
+	}
+	return tags
+}
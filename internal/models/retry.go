@@ -0,0 +1,172 @@
+package models
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+const (
+	// Default maximum time between two attempts to write to a failing output.
+	DEFAULT_RETRY_MAX_INTERVAL = time.Minute
+
+	// Default time the circuit breaker stays open.
+	DEFAULT_CIRCUIT_BREAKER_TIMEOUT = 30 * time.Second
+)
+
+// RetryConfig is the policy used by a RunningOutput when writes fail.
+type RetryConfig struct {
+	// MaxAttempts is the number of times the oldest batch is attempted
+	// before it is dropped. 0 retries forever.
+	MaxAttempts int
+
+	// InitialInterval is the time to wait after the first failed write. The
+	// wait doubles after every consecutive failure, up to MaxInterval.
+	// 0 retries on every flush.
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+
+	// BreakerThreshold is the number of consecutive failed writes that opens
+	// the circuit breaker. 0 disables the circuit breaker.
+	BreakerThreshold int
+	// BreakerTimeout is how long the circuit breaker stays open before a
+	// single trial write is allowed.
+	BreakerTimeout time.Duration
+}
+
+// Circuit breaker states, as reported by the circuit_state stat.
+const (
+	breakerClosed = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// retrier tracks the failed writes of an output and decides when the next
+// write may be attempted.
+type retrier struct {
+	config RetryConfig
+
+	state    int
+	failures int
+	attempts int
+	next     time.Time
+
+	// now is replaced in tests.
+	now func() time.Time
+
+	mu sync.Mutex
+}
+
+func newRetrier(config RetryConfig) *retrier {
+	if config.InitialInterval > 0 && config.MaxInterval == 0 {
+		config.MaxInterval = DEFAULT_RETRY_MAX_INTERVAL
+	}
+	if config.BreakerThreshold > 0 && config.BreakerTimeout == 0 {
+		config.BreakerTimeout = DEFAULT_CIRCUIT_BREAKER_TIMEOUT
+	}
+	return &retrier{
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// allow returns an error if the output must not be written to yet.
+func (r *retrier) allow() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	switch r.state {
+	case breakerOpen:
+		if now.Before(r.next) {
+			return fmt.Errorf("circuit breaker open, next attempt in %s",
+				r.next.Sub(now))
+		}
+		r.state = breakerHalfOpen
+	case breakerClosed:
+		if now.Before(r.next) {
+			return fmt.Errorf("retrying after %d failed writes in %s",
+				r.failures, r.next.Sub(now))
+		}
+	}
+	return nil
+}
+
+// success records a successful write.
+func (r *retrier) success() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.state = breakerClosed
+	r.failures = 0
+	r.attempts = 0
+	r.next = time.Time{}
+}
+
+// failure records a failed write of the oldest batch, and returns true if
+// the batch should be dropped.
+func (r *retrier) failure(err error) bool {
+	if isPermanent(err) {
+		r.mu.Lock()
+		r.attempts = 0
+		r.mu.Unlock()
+		return true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	r.failures++
+	r.attempts++
+
+	switch {
+	case r.state == breakerHalfOpen:
+		r.state = breakerOpen
+		r.next = now.Add(r.config.BreakerTimeout)
+	case r.config.BreakerThreshold > 0 && r.failures >= r.config.BreakerThreshold:
+		r.state = breakerOpen
+		r.next = now.Add(r.config.BreakerTimeout)
+	default:
+		r.next = now.Add(r.backoff())
+	}
+
+	if r.config.MaxAttempts > 0 && r.attempts >= r.config.MaxAttempts {
+		r.attempts = 0
+		return true
+	}
+	return false
+}
+
+// backoff returns the wait before the next attempt: the initial interval
+// doubled for each consecutive failure, capped to the max interval, and
+// jittered between half and all of that.
+func (r *retrier) backoff() time.Duration {
+	if r.config.InitialInterval <= 0 {
+		return 0
+	}
+	d := r.config.InitialInterval
+	for i := 1; i < r.failures && d < r.config.MaxInterval; i++ {
+		d *= 2
+	}
+	if d > r.config.MaxInterval {
+		d = r.config.MaxInterval
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// State returns the state of the circuit breaker.
+func (r *retrier) State() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state
+}
+
+func isPermanent(err error) bool {
+	if perr, ok := err.(telegraf.PermanentError); ok {
+		return perr.Permanent()
+	}
+	return false
+}
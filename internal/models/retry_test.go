@@ -0,0 +1,109 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type permanentError struct{}
+
+func (e permanentError) Error() string   { return "bad request" }
+func (e permanentError) Permanent() bool { return true }
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func newTestRetrier(config RetryConfig) (*retrier, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	r := newRetrier(config)
+	r.now = clock.now
+	return r, clock
+}
+
+func TestRetrierDefaultAlwaysAllows(t *testing.T) {
+	r, _ := newTestRetrier(RetryConfig{})
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, r.allow())
+		assert.False(t, r.failure(errors.New("timeout")))
+	}
+	assert.Equal(t, breakerClosed, r.State())
+}
+
+func TestRetrierBackoff(t *testing.T) {
+	r, clock := newTestRetrier(RetryConfig{
+		InitialInterval: time.Second,
+		MaxInterval:     4 * time.Second,
+	})
+
+	for _, max := range []time.Duration{1, 2, 4, 4} {
+		require.NoError(t, r.allow())
+		r.failure(errors.New("timeout"))
+
+		wait := r.next.Sub(clock.t)
+		assert.True(t, wait >= max*time.Second/2, "wait %s too short", wait)
+		assert.True(t, wait <= max*time.Second, "wait %s too long", wait)
+
+		clock.t = clock.t.Add(wait / 2)
+		assert.Error(t, r.allow())
+		clock.t = r.next
+	}
+
+	r.success()
+	assert.NoError(t, r.allow())
+}
+
+func TestRetrierMaxAttempts(t *testing.T) {
+	r, _ := newTestRetrier(RetryConfig{MaxAttempts: 3})
+
+	assert.False(t, r.failure(errors.New("timeout")))
+	assert.False(t, r.failure(errors.New("timeout")))
+	assert.True(t, r.failure(errors.New("timeout")))
+	// the next batch gets its own attempts
+	assert.False(t, r.failure(errors.New("timeout")))
+}
+
+func TestRetrierPermanentError(t *testing.T) {
+	r, _ := newTestRetrier(RetryConfig{BreakerThreshold: 1})
+
+	assert.True(t, r.failure(permanentError{}))
+	assert.Equal(t, breakerClosed, r.State())
+	assert.NoError(t, r.allow())
+}
+
+func TestRetrierCircuitBreaker(t *testing.T) {
+	r, clock := newTestRetrier(RetryConfig{
+		BreakerThreshold: 2,
+		BreakerTimeout:   time.Minute,
+	})
+
+	r.failure(errors.New("timeout"))
+	assert.Equal(t, breakerClosed, r.State())
+	r.failure(errors.New("timeout"))
+	assert.Equal(t, breakerOpen, r.State())
+	assert.Error(t, r.allow())
+
+	// a failed trial write opens the breaker again
+	clock.t = clock.t.Add(time.Minute)
+	require.NoError(t, r.allow())
+	assert.Equal(t, breakerHalfOpen, r.State())
+	r.failure(errors.New("timeout"))
+	assert.Equal(t, breakerOpen, r.State())
+	assert.Error(t, r.allow())
+
+	// a successful trial write closes it
+	clock.t = clock.t.Add(time.Minute)
+	require.NoError(t, r.allow())
+	r.success()
+	assert.Equal(t, breakerClosed, r.State())
+	assert.NoError(t, r.allow())
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"log"
+
+	"github.com/influxdata/telegraf"
+)
+
+// RouteConfig is a rule of the routing table, sending the metrics accepted by
+// Filter to the outputs named in Outputs.
+type RouteConfig struct {
+	Filter  Filter
+	Outputs []string
+}
+
+// Router decides which outputs a metric is sent to.
+//
+// Outputs named by a route only receive the metrics matching one of the
+// routes naming them. Outputs not named by any route receive every metric, as
+// if there were no routing table.
+type Router struct {
+	routes  []*route
+	outputs []*RunningOutput
+
+	// unrouted holds the outputs that are not named by any route.
+	unrouted []int
+}
+
+type route struct {
+	filter  Filter
+	outputs []int
+}
+
+// NewRouter returns the Router for routes. Outputs are referenced by their
+// alias, or by their plugin name when they have no alias; a route naming
+// several outputs with the same name sends to all of them.
+func NewRouter(routes []*RouteConfig, outputs []*RunningOutput) *Router {
+	r := &Router{outputs: outputs}
+
+	routed := make([]bool, len(outputs))
+	for _, rc := range routes {
+		rt := &route{filter: rc.Filter}
+		for _, name := range rc.Outputs {
+			found := false
+			for i, o := range outputs {
+				if outputID(o) == name {
+					rt.outputs = append(rt.outputs, i)
+					routed[i] = true
+					found = true
+				}
+			}
+			if !found {
+				log.Printf("W! Route to unknown output %s, ignoring\n", name)
+			}
+		}
+		r.routes = append(r.routes, rt)
+	}
+
+	for i := range outputs {
+		if !routed[i] {
+			r.unrouted = append(r.unrouted, i)
+		}
+	}
+	return r
+}
+
+// Route returns the outputs that the metric must be sent to. Each output is
+// returned at most once.
+func (r *Router) Route(m telegraf.Metric) []*RunningOutput {
+	if len(r.routes) == 0 {
+		return r.outputs
+	}
+
+	selected := make([]bool, len(r.outputs))
+	for _, i := range r.unrouted {
+		selected[i] = true
+	}
+	for _, rt := range r.routes {
+		if rt.match(m) {
+			for _, i := range rt.outputs {
+				selected[i] = true
+			}
+		}
+	}
+
+	outputs := make([]*RunningOutput, 0, len(r.outputs))
+	for i, ok := range selected {
+		if ok {
+			outputs = append(outputs, r.outputs[i])
+		}
+	}
+	return outputs
+}
+
+// match returns true if the route's filter accepts the metric. Unlike
+// Filter.Apply, it does not modify the metric; the field filters accept the
+// metric if any of its fields passes.
+func (rt *route) match(m telegraf.Metric) bool {
+	f := &rt.filter
+	if !f.IsActive() {
+		return true
+	}
+
+	if !f.shouldNamePass(m.Name()) {
+		return false
+	}
+	if !f.shouldTagsPass(m.Tags()) {
+		return false
+	}
+
+	if f.fieldPass == nil && f.fieldDrop == nil {
+		return true
+	}
+	for key := range m.Fields() {
+		if f.shouldFieldPass(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// outputID returns the name that routes use to reference an output.
+func outputID(o *RunningOutput) string {
+	if o.Config != nil && o.Config.Alias != "" {
+		return o.Config.Alias
+	}
+	return o.Name
+}
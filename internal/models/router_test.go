@@ -0,0 +1,120 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRouterOutput(name, alias string) *RunningOutput {
+	return NewRunningOutput(name, &mockOutput{},
+		&OutputConfig{Name: name, Alias: alias}, 0, 0)
+}
+
+func newRoute(t *testing.T, f Filter, outputs ...string) *RouteConfig {
+	require.NoError(t, f.Compile())
+	return &RouteConfig{Filter: f, Outputs: outputs}
+}
+
+func routeMetric(t *testing.T, name string, tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	m, err := metric.New(name, tags, fields, time.Unix(0, 0))
+	require.NoError(t, err)
+	return m
+}
+
+func routedNames(outputs []*RunningOutput) []string {
+	var names []string
+	for _, o := range outputs {
+		names = append(names, outputID(o))
+	}
+	return names
+}
+
+func TestRouterWithoutRoutes(t *testing.T) {
+	outputs := []*RunningOutput{
+		newRouterOutput("influxdb", ""),
+		newRouterOutput("file", ""),
+	}
+	r := NewRouter(nil, outputs)
+
+	m := routeMetric(t, "cpu", nil, map[string]interface{}{"value": 1})
+	assert.Equal(t, []string{"influxdb", "file"}, routedNames(r.Route(m)))
+}
+
+func TestRouterRoutes(t *testing.T) {
+	outputs := []*RunningOutput{
+		newRouterOutput("influxdb", "primary"),
+		newRouterOutput("influxdb", "secondary"),
+		newRouterOutput("file", ""),
+	}
+	r := NewRouter([]*RouteConfig{
+		newRoute(t, Filter{NamePass: []string{"cpu*"}}, "primary"),
+		newRoute(t, Filter{
+			TagPass: []TagFilter{{Name: "host", Filter: []string{"web*"}}},
+		}, "primary", "secondary"),
+		newRoute(t, Filter{FieldPass: []string{"usage_*"}}, "secondary"),
+	}, outputs)
+
+	tests := []struct {
+		name     string
+		metric   telegraf.Metric
+		expected []string
+	}{
+		{
+			name: "no match only goes to unrouted outputs",
+			metric: routeMetric(t, "mem", map[string]string{"host": "db01"},
+				map[string]interface{}{"free": 1}),
+			expected: []string{"file"},
+		},
+		{
+			name: "name match",
+			metric: routeMetric(t, "cpu", map[string]string{"host": "db01"},
+				map[string]interface{}{"idle": 1}),
+			expected: []string{"primary", "file"},
+		},
+		{
+			name: "outputs of several routes are returned once",
+			metric: routeMetric(t, "cpu", map[string]string{"host": "web01"},
+				map[string]interface{}{"idle": 1}),
+			expected: []string{"primary", "secondary", "file"},
+		},
+		{
+			name: "field match",
+			metric: routeMetric(t, "mem", nil,
+				map[string]interface{}{"free": 1, "usage_percent": 1}),
+			expected: []string{"secondary", "file"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, routedNames(r.Route(tt.metric)))
+		})
+	}
+}
+
+func TestRouterDoesNotModifyMetric(t *testing.T) {
+	outputs := []*RunningOutput{newRouterOutput("file", "")}
+	r := NewRouter([]*RouteConfig{
+		newRoute(t, Filter{FieldPass: []string{"usage_*"}}, "file"),
+	}, outputs)
+
+	m := routeMetric(t, "cpu", nil,
+		map[string]interface{}{"idle": 1, "usage_user": 1})
+	require.Len(t, r.Route(m), 1)
+	assert.Len(t, m.Fields(), 2)
+}
+
+func TestRouterUnknownOutput(t *testing.T) {
+	outputs := []*RunningOutput{newRouterOutput("file", "")}
+	r := NewRouter([]*RouteConfig{
+		newRoute(t, Filter{NamePass: []string{"cpu"}}, "missing"),
+	}, outputs)
+
+	m := routeMetric(t, "cpu", nil, map[string]interface{}{"value": 1})
+	assert.Equal(t, []string{"file"}, routedNames(r.Route(m)))
+}
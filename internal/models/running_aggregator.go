@@ -12,6 +12,7 @@ type RunningAggregator struct {
 	Config *AggregatorConfig
 
 	metrics chan telegraf.Metric
+	log     telegraf.Logger
 
 	periodStart time.Time
 	periodEnd   time.Time
@@ -25,13 +26,16 @@ func NewRunningAggregator(
 		a:       a,
 		Config:  conf,
 		metrics: make(chan telegraf.Metric, 100),
+		log: newLogger(a, "aggregators."+conf.Name, conf.Alias,
+			conf.LogLevel),
 	}
 }
 
 // AggregatorConfig containing configuration parameters for the running
 // aggregator plugin.
 type AggregatorConfig struct {
-	Name string
+	Name  string
+	Alias string
 
 	DropOriginal      bool
 	NameOverride      string
@@ -42,12 +46,26 @@ type AggregatorConfig struct {
 
 	Period time.Duration
 	Delay  time.Duration
+
+	// LogLevel overrides the log level of the agent for the aggregator.
+	LogLevel string
 }
 
 func (r *RunningAggregator) Name() string {
 	return "aggregators." + r.Config.Name
 }
 
+// LogName returns the name of the aggregator in log messages, including its
+// alias.
+func (r *RunningAggregator) LogName() string {
+	return logName(r.Name(), r.Config.Alias)
+}
+
+// Log returns the logger of the aggregator.
+func (r *RunningAggregator) Log() telegraf.Logger {
+	return r.log
+}
+
 func (r *RunningAggregator) MakeMetric(
 	measurement string,
 	fields map[string]interface{},
@@ -81,6 +99,10 @@ func (r *RunningAggregator) MakeMetric(
 // Before applying to the plugin, it will run any defined filters on the metric.
 // Apply returns true if the original metric should be dropped.
 func (r *RunningAggregator) Add(in telegraf.Metric) bool {
+	// the aggregator only keeps the values of the metric, which is handled
+	// as far as delivery tracking is concerned.
+	in.Drop()
+
 	if r.Config.Filter.IsActive() {
 		// check if the aggregator should apply this metric
 		name := in.Name()
@@ -2,9 +2,11 @@ package models
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
@@ -13,43 +15,174 @@ var GlobalMetricsGathered = selfstat.Register("agent", "metrics_gathered", map[s
 type RunningInput struct {
 	Input  telegraf.Input
 	Config *InputConfig
+	// Checksum identifies the configuration of the input, to find the inputs
+	// that did not change when the configuration is reloaded.
+	Checksum uint64
 
 	trace       bool
 	defaultTags map[string]string
 
 	MetricsGathered selfstat.Stat
+	GatherErrors    selfstat.Stat
+	GathersSkipped  selfstat.Stat
+	GatherTimeouts  selfstat.Stat
+	// SeriesLimitDropped and SeriesLimitRewritten count the metrics of the
+	// series over max_series.
+	SeriesLimitDropped   selfstat.Stat
+	SeriesLimitRewritten selfstat.Stat
+	// LastError is the time of the last gather error, in nanoseconds since
+	// the epoch.
+	LastError selfstat.Stat
+
+	log     telegraf.Logger
+	limiter *seriesLimiter
+
+	mu        sync.Mutex
+	lastErr   error
+	gathering bool
 }
 
 func NewRunningInput(
 	input telegraf.Input,
 	config *InputConfig,
 ) *RunningInput {
-	return &RunningInput{
+	tags := statTags("input", config.Name, config.Alias)
+	r := &RunningInput{
 		Input:  input,
 		Config: config,
+		log: newLogger(input, "inputs."+config.Name, config.Alias,
+			config.LogLevel),
 		MetricsGathered: selfstat.Register(
 			"gather",
 			"metrics_gathered",
-			map[string]string{"input": config.Name},
+			tags,
+		),
+		GatherErrors: selfstat.Register(
+			"gather",
+			"errors",
+			tags,
+		),
+		LastError: selfstat.Register(
+			"gather",
+			"last_error_unix_ns",
+			tags,
+		),
+		GathersSkipped: selfstat.Register(
+			"gather",
+			"gathers_skipped",
+			tags,
+		),
+		GatherTimeouts: selfstat.Register(
+			"gather",
+			"gather_timeouts",
+			tags,
 		),
+		SeriesLimitDropped: selfstat.Register(
+			"gather",
+			"metrics_dropped_series_limit",
+			tags,
+		),
+		SeriesLimitRewritten: selfstat.Register(
+			"gather",
+			"metrics_rewritten_series_limit",
+			tags,
+		),
+	}
+	if config.SeriesLimit.MaxSeries > 0 {
+		r.limiter = newSeriesLimiter(config.SeriesLimit)
 	}
+	return r
 }
 
 // InputConfig containing a name, interval, and filter
 type InputConfig struct {
 	Name              string
+	Alias             string
 	NameOverride      string
 	MeasurementPrefix string
 	MeasurementSuffix string
 	Tags              map[string]string
 	Filter            Filter
 	Interval          time.Duration
+	// GatherTimeout is the time after which a gather is abandoned, the
+	// interval by default.
+	GatherTimeout time.Duration
+	// CollectionJitter overrides the collection jitter of the agent for the
+	// input.
+	CollectionJitter time.Duration
+	// CollectionOffset delays the gathering of the input by this much after
+	// the start of each collection interval.
+	CollectionOffset time.Duration
+	// Precision overrides the precision of the agent for the input.
+	Precision time.Duration
+	// AlignTimestamps stamps the metrics added without a timestamp with the
+	// start of their collection interval instead of the current time.
+	AlignTimestamps bool
+	// LogLevel overrides the log level of the agent for the input.
+	LogLevel string
+	// SeriesLimit limits the number of series created by the input.
+	SeriesLimit SeriesLimitConfig
 }
 
 func (r *RunningInput) Name() string {
 	return "inputs." + r.Config.Name
 }
 
+// LogName returns the name of the input in log messages, including its alias.
+func (r *RunningInput) LogName() string {
+	return logName(r.Name(), r.Config.Alias)
+}
+
+// Log returns the logger of the input.
+func (r *RunningInput) Log() telegraf.Logger {
+	return r.log
+}
+
+// StatTags returns the selfstat tags identifying the input.
+func (r *RunningInput) StatTags() map[string]string {
+	return statTags("input", r.Config.Name, r.Config.Alias)
+}
+
+// BeginGather marks a gather of the input as in flight. It returns false if
+// one already is, in which case the input must not be gathered.
+func (r *RunningInput) BeginGather() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.gathering {
+		return false
+	}
+	r.gathering = true
+	return true
+}
+
+// EndGather marks the gather started by BeginGather as completed.
+func (r *RunningInput) EndGather() {
+	r.mu.Lock()
+	r.gathering = false
+	r.mu.Unlock()
+}
+
+// RecordError records an error reported by the input while gathering.
+func (r *RunningInput) RecordError(err error) {
+	r.mu.Lock()
+	r.lastErr = err
+	r.mu.Unlock()
+	r.GatherErrors.Incr(1)
+	r.LastError.Set(time.Now().UnixNano())
+}
+
+// LastGatherError returns the time of the last error recorded by
+// RecordError, and the error. The time is zero if the input never reported an error.
+func (r *RunningInput) LastGatherError() (time.Time, error) {
+	r.mu.Lock()
+	err := r.lastErr
+	r.mu.Unlock()
+	if err == nil {
+		return time.Time{}, nil
+	}
+	return time.Unix(0, r.LastError.Get()), err
+}
+
 // MakeMetric either returns a metric, or returns nil if the metric doesn't
 // need to be created (because of filtering, an error, etc.)
 func (r *RunningInput) MakeMetric(
@@ -74,8 +207,12 @@ func (r *RunningInput) MakeMetric(
 		t,
 	)
 
+	if m != nil && r.limiter != nil {
+		m = r.limitSeries(m)
+	}
+
 	if r.trace && m != nil {
-		fmt.Print("> " + m.String())
+		fmt.Print("> " + internal.Redact(m.String()))
 	}
 
 	r.MetricsGathered.Incr(1)
@@ -83,6 +220,29 @@ func (r *RunningInput) MakeMetric(
 	return m
 }
 
+// limitSeries returns the metric, rewritten if its series is over the limit,
+// or nil if it must be dropped.
+func (r *RunningInput) limitSeries(m telegraf.Metric) telegraf.Metric {
+	result, first := r.limiter.limit(m)
+	switch result {
+	case seriesDropped:
+		if first {
+			r.log.Warnf("Limit of %d series reached, dropping the metrics of new series",
+				r.Config.SeriesLimit.MaxSeries)
+		}
+		r.SeriesLimitDropped.Incr(1)
+		return nil
+	case seriesRewritten:
+		if first {
+			r.log.Warnf("Limit of %d series reached, rewriting the tags %v of new series to %q",
+				r.Config.SeriesLimit.MaxSeries, r.Config.SeriesLimit.RewriteTags,
+				SERIES_OTHER_VALUE)
+		}
+		r.SeriesLimitRewritten.Incr(1)
+	}
+	return m
+}
+
 func (r *RunningInput) Trace() bool {
 	return r.trace
 }
@@ -337,3 +337,54 @@ type testInput struct{}
 func (t *testInput) Description() string                   { return "" }
 func (t *testInput) SampleConfig() string                  { return "" }
 func (t *testInput) Gather(acc telegraf.Accumulator) error { return nil }
+
+func TestRunningInputAlias(t *testing.T) {
+	ri := NewRunningInput(&testInput{}, &InputConfig{
+		Name:  "http_response",
+		Alias: "frontend",
+	})
+	assert.Equal(t, "inputs.http_response", ri.Name())
+	assert.Equal(t, "inputs.http_response::frontend", ri.LogName())
+	assert.Equal(t,
+		map[string]string{"input": "http_response", "alias": "frontend"},
+		ri.MetricsGathered.Tags())
+
+	ri = NewRunningInput(&testInput{}, &InputConfig{Name: "http_response"})
+	assert.Equal(t, "inputs.http_response", ri.LogName())
+	assert.Equal(t, map[string]string{"input": "http_response"},
+		ri.MetricsGathered.Tags())
+}
+
+type loggingInput struct {
+	testInput
+	Log telegraf.Logger `toml:"-"`
+}
+
+func TestRunningInputSetsLogger(t *testing.T) {
+	input := &loggingInput{}
+	ri := NewRunningInput(input, &InputConfig{Name: "cpu", Alias: "local"})
+	assert.NotNil(t, input.Log)
+	assert.Equal(t, ri.Log(), input.Log)
+
+	// inputs without a Log field are left alone
+	NewRunningInput(&testInput{}, &InputConfig{Name: "cpu"})
+}
+
+func TestMakeMetricSeriesLimit(t *testing.T) {
+	ri := NewRunningInput(&testInput{}, &InputConfig{
+		Name:        "TestRunningInput",
+		SeriesLimit: SeriesLimitConfig{MaxSeries: 1},
+	})
+
+	fields := map[string]interface{}{"value": int(101)}
+	m := ri.MakeMetric("RITest", fields, map[string]string{"id": "1"},
+		telegraf.Untyped, time.Now())
+	assert.NotNil(t, m)
+	m = ri.MakeMetric("RITest", fields, map[string]string{"id": "2"},
+		telegraf.Untyped, time.Now())
+	assert.Nil(t, m)
+	m = ri.MakeMetric("RITest", fields, map[string]string{"id": "1"},
+		telegraf.Untyped, time.Now())
+	assert.NotNil(t, m)
+	assert.Equal(t, int64(1), ri.SeriesLimitDropped.Get())
+}
@@ -1,15 +1,27 @@
 package models
 
 import (
-	"log"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal/buffer"
-	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
+// errAbandoned is returned by write when the write was abandoned at
+// shutdown, the buffers must then be left as they are.
+var errAbandoned = errors.New("write abandoned at shutdown")
+
+// errDropped is returned by write when the batch was dropped after its last
+// attempt. The remaining batches wait for the next attempt the retry policy
+// allows.
+var errDropped = errors.New("batch dropped")
+
 const (
 	// Default size of metrics batch size.
 	DEFAULT_METRIC_BATCH_SIZE = 1000
@@ -25,15 +37,53 @@ type RunningOutput struct {
 	Config            *OutputConfig
 	MetricBufferLimit int
 	MetricBatchSize   int
+	// Checksum identifies the configuration of the output, to find the
+	// outputs that did not change when the configuration is reloaded.
+	Checksum uint64
+
+	// BatchReady receives a value when a full batch of metrics is waiting to
+	// be written and the output is configured to flush when full.
+	BatchReady chan struct{}
 
 	MetricsFiltered selfstat.Stat
 	MetricsWritten  selfstat.Stat
+	MetricsDropped  selfstat.Stat
 	BufferSize      selfstat.Stat
 	BufferLimit     selfstat.Stat
 	WriteTime       selfstat.Stat
+	WriteErrors     selfstat.Stat
+	WritesSkipped   selfstat.Stat
+	CircuitState    selfstat.Stat
+	// LastWrite is the time of the last successful write, in nanoseconds
+	// since the epoch.
+	LastWrite selfstat.Stat
 
 	metrics     *buffer.Buffer
-	failMetrics *buffer.Buffer
+	failMetrics metricBuffer
+	retry       *retrier
+	log         telegraf.Logger
+
+	// writing is the number of metrics of the write in progress, accessed
+	// atomically.
+	writing int32
+	// bufferMu is held by WriteContext while it uses the buffers, and is only
+	// released while the output is being written to. PersistBuffer takes it
+	// to increment persisted, so that a write abandoned at shutdown leaves
+	// the buffers alone when it completes.
+	bufferMu  sync.Mutex
+	persisted uint64
+	// circuitState is the last logged state of the circuit breaker, accessed
+	// atomically.
+	circuitState int32
+}
+
+// metricBuffer is implemented by both the in-memory buffer.Buffer and the
+// durable buffer.DiskBuffer.
+type metricBuffer interface {
+	IsEmpty() bool
+	Len() int
+	Add(metrics ...telegraf.Metric)
+	Batch(batchSize int) []telegraf.Metric
 }
 
 func NewRunningOutput(
@@ -49,6 +99,7 @@ func NewRunningOutput(
 	if batchSize == 0 {
 		batchSize = DEFAULT_METRIC_BATCH_SIZE
 	}
+	tags := statTags("output", name, conf.Alias)
 	ro := &RunningOutput{
 		Name:              name,
 		metrics:           buffer.NewBuffer(batchSize),
@@ -57,75 +108,209 @@ func NewRunningOutput(
 		Config:            conf,
 		MetricBufferLimit: bufferLimit,
 		MetricBatchSize:   batchSize,
+		BatchReady:        make(chan struct{}, 1),
+		retry:             newRetrier(conf.Retry),
+		log: newLogger(output, "outputs."+name, conf.Alias,
+			conf.LogLevel),
 		MetricsWritten: selfstat.Register(
 			"write",
 			"metrics_written",
-			map[string]string{"output": name},
+			tags,
 		),
 		MetricsFiltered: selfstat.Register(
 			"write",
 			"metrics_filtered",
-			map[string]string{"output": name},
+			tags,
+		),
+		MetricsDropped: selfstat.Register(
+			"write",
+			"metrics_dropped",
+			tags,
 		),
 		BufferSize: selfstat.Register(
 			"write",
 			"buffer_size",
-			map[string]string{"output": name},
+			tags,
 		),
 		BufferLimit: selfstat.Register(
 			"write",
 			"buffer_limit",
-			map[string]string{"output": name},
+			tags,
 		),
 		WriteTime: selfstat.RegisterTiming(
 			"write",
 			"write_time_ns",
-			map[string]string{"output": name},
+			tags,
+		),
+		WriteErrors: selfstat.Register(
+			"write",
+			"write_errors",
+			tags,
+		),
+		WritesSkipped: selfstat.Register(
+			"write",
+			"writes_skipped",
+			tags,
+		),
+		CircuitState: selfstat.Register(
+			"write",
+			"circuit_state",
+			tags,
+		),
+		LastWrite: selfstat.Register(
+			"write",
+			"last_write_unix_ns",
+			tags,
 		),
 	}
-	ro.BufferLimit.Incr(int64(ro.MetricBufferLimit))
+	ro.BufferLimit.Set(int64(ro.MetricBufferLimit))
 	return ro
 }
 
-// AddMetric adds a metric to the output. This function can also write cached
-// points if FlushBufferWhenFull is true.
+// Connect connects the output. When the output has a buffer directory
+// configured, the disk buffer is opened first, so that a buffer directory is
+// only ever opened by an output that is running.
+func (ro *RunningOutput) Connect() error {
+	ro.openBuffer()
+	return ro.Output.Connect()
+}
+
+// openBuffer replaces the in-memory buffer of failed writes by the disk
+// buffer, when the output has a buffer directory configured.
+func (ro *RunningOutput) openBuffer() {
+	if ro.Config.BufferDirectory == "" {
+		return
+	}
+	if _, ok := ro.failMetrics.(*buffer.DiskBuffer); ok {
+		return
+	}
+	b, err := buffer.NewDiskBuffer(ro.Config.BufferDirectory,
+		ro.Config.BufferMaxSize)
+	if err != nil {
+		ro.log.Errorf("Unable to open disk buffer %s (%s), using in-memory buffer",
+			ro.Config.BufferDirectory, err)
+		return
+	}
+	ro.bufferMu.Lock()
+	defer ro.bufferMu.Unlock()
+	if n := ro.failMetrics.Len(); n > 0 {
+		b.Add(ro.failMetrics.Batch(n)...)
+	}
+	ro.failMetrics = b
+}
+
+// BufferFill returns the ratio of the buffer of the output in use. With a
+// disk buffer, it is the ratio of buffer_max_size used on disk, which is not
+// bounded by metric_buffer_limit.
+func (ro *RunningOutput) BufferFill() float64 {
+	ro.bufferMu.Lock()
+	db, ok := ro.failMetrics.(*buffer.DiskBuffer)
+	ro.bufferMu.Unlock()
+	if ok {
+		return float64(db.Size()) / float64(db.MaxSize())
+	}
+
+	limit := ro.BufferLimit.Get()
+	if limit <= 0 {
+		return 0
+	}
+	return float64(ro.BufferSize.Get()) / float64(limit)
+}
+
+// Log returns the logger of the output.
+func (ro *RunningOutput) Log() telegraf.Logger {
+	return ro.log
+}
+
+// LogName returns the name of the output in log messages, including its alias.
+func (ro *RunningOutput) LogName() string {
+	return logName(ro.Name, ro.Config.Alias)
+}
+
+// AddMetric adds a metric to the output. AddMetric never writes to the
+// output: when a full batch is buffered, it signals BatchReady if the output
+// is configured to flush when full.
 func (ro *RunningOutput) AddMetric(m telegraf.Metric) {
 	if m == nil {
 		return
 	}
 	// Filter any tagexclude/taginclude parameters before adding metric
 	if ro.Config.Filter.IsActive() {
-		// In order to filter out tags, we need to create a new metric, since
-		// metrics are immutable once created.
 		name := m.Name()
 		tags := m.Tags()
 		fields := m.Fields()
-		t := m.Time()
 		if ok := ro.Config.Filter.Apply(name, fields, tags); !ok {
 			ro.MetricsFiltered.Incr(1)
+			m.Drop()
 			return
 		}
-		// error is not possible if creating from another metric, so ignore.
-		m, _ = metric.New(name, tags, fields, t)
+		// the filtered out tags and fields are removed from a copy, which
+		// keeps the delivery tracking of the metric.
+		filtered := m.Copy()
+		for k := range m.Tags() {
+			if _, ok := tags[k]; !ok {
+				filtered.RemoveTag(k)
+			}
+		}
+		for k := range m.Fields() {
+			if _, ok := fields[k]; !ok {
+				filtered.RemoveField(k)
+			}
+		}
+		m.Drop()
+		m = filtered
 	}
 
 	ro.metrics.Add(m)
 	if ro.metrics.Len() == ro.MetricBatchSize {
+		// move the batch to the main buffer, ahead of any new metric, to be
+		// written by the next call to Write.
 		batch := ro.metrics.Batch(ro.MetricBatchSize)
-		err := ro.write(batch)
-		if err != nil {
-			ro.failMetrics.Add(batch...)
+		ro.failMetrics.Add(batch...)
+		if ro.Config.FlushWhenFull {
+			select {
+			case ro.BatchReady <- struct{}{}:
+			default:
+			}
 		}
 	}
 }
 
 // Write writes all cached points to this output.
 func (ro *RunningOutput) Write() error {
+	return ro.WriteContext(context.Background())
+}
+
+// WriteContext writes all cached points to this output, passing ctx to
+// outputs implementing telegraf.ContextOutput. The batches that could not be
+// written when ctx is cancelled stay buffered.
+func (ro *RunningOutput) WriteContext(ctx context.Context) error {
+	ro.bufferMu.Lock()
+	defer ro.bufferMu.Unlock()
+
 	nFails, nMetrics := ro.failMetrics.Len(), ro.metrics.Len()
 	ro.BufferSize.Set(int64(nFails + nMetrics))
-	log.Printf("D! Output [%s] buffer fullness: %d / %d metrics. ",
-		ro.Name, nFails+nMetrics, ro.MetricBufferLimit)
-	var err error
+	ro.log.Debugf("Buffer fullness: %d / %d metrics",
+		nFails+nMetrics, ro.MetricBufferLimit)
+
+	// the retry policy may hold off writes after failures. The skipped
+	// writes are not errors, the state changes are logged instead.
+	err := ro.retry.allow()
+	ro.updateCircuitState()
+	if err != nil {
+		ro.WritesSkipped.Incr(1)
+		ro.log.Debugf("Skipping write: %s", err)
+		return nil
+	}
+
+	if db, ok := ro.failMetrics.(*buffer.DiskBuffer); ok {
+		err = ro.writeDiskBuffer(ctx, db)
+		if err == errAbandoned {
+			return nil
+		}
+		return err
+	}
+
 	if !ro.failMetrics.IsEmpty() {
 		// how many batches of failed writes we need to write.
 		nBatches := nFails/ro.MetricBatchSize + 1
@@ -142,7 +327,13 @@ func (ro *RunningOutput) Write() error {
 			// write to this output again. We are not exiting the loop just so
 			// that we can rotate the metrics to preserve order.
 			if err == nil {
-				err = ro.write(batch)
+				err = ro.write(ctx, batch)
+				if err == errDropped {
+					continue
+				}
+			}
+			if err == errAbandoned {
+				return nil
 			}
 			if err != nil {
 				ro.failMetrics.Add(batch...)
@@ -154,29 +345,197 @@ func (ro *RunningOutput) Write() error {
 	// see comment above about not trying to write to an already failed output.
 	// if ro.failMetrics is empty then err will always be nil at this point.
 	if err == nil {
-		err = ro.write(batch)
+		err = ro.write(ctx, batch)
+		if err == errDropped {
+			return nil
+		}
+	}
+	if err == errAbandoned {
+		return nil
 	}
 
 	if err != nil {
 		ro.failMetrics.Add(batch...)
+		if err == errDropped {
+			return nil
+		}
 		return err
 	}
 	return nil
 }
 
-func (ro *RunningOutput) write(metrics []telegraf.Metric) error {
+// writeDiskBuffer writes all the metrics through the disk buffer. The
+// batches are only removed from the disk once written, so a batch being
+// written when telegraf stops, or crashes, is written again.
+func (ro *RunningOutput) writeDiskBuffer(
+	ctx context.Context,
+	db *buffer.DiskBuffer,
+) error {
+	db.Add(ro.metrics.Batch(ro.MetricBatchSize)...)
+
+	nBatches := db.Len()/ro.MetricBatchSize + 1
+	for i := 0; i < nBatches && !db.IsEmpty(); i++ {
+		// the batch stays on disk until written, even if the write is
+		// abandoned at shutdown.
+		batch := db.Peek(ro.MetricBatchSize)
+		if err := ro.write(ctx, batch); err != nil {
+			if err == errDropped {
+				db.Commit()
+				return nil
+			}
+			return err
+		}
+		db.Commit()
+	}
+	return nil
+}
+
+func (ro *RunningOutput) write(
+	ctx context.Context,
+	metrics []telegraf.Metric,
+) error {
 	nMetrics := len(metrics)
 	if nMetrics == 0 {
 		return nil
 	}
+
+	// the buffers are not used while the output is written to, so that the
+	// write can be abandoned at shutdown.
+	atomic.StoreInt32(&ro.writing, int32(nMetrics))
+	persisted := ro.persisted
+	ro.bufferMu.Unlock()
 	start := time.Now()
-	err := ro.Output.Write(metrics)
+	var err error
+	if co, ok := ro.Output.(telegraf.ContextOutput); ok {
+		err = co.WriteContext(ctx, metrics)
+	} else {
+		err = ro.Output.Write(metrics)
+	}
 	elapsed := time.Since(start)
+	ro.bufferMu.Lock()
+	atomic.StoreInt32(&ro.writing, 0)
+
+	if ro.persisted != persisted {
+		// PersistBuffer already accounted for the metrics.
+		for _, m := range metrics {
+			if err == nil {
+				m.Accept()
+			} else {
+				m.Reject()
+			}
+		}
+		return errAbandoned
+	}
 	if err == nil {
-		log.Printf("D! Output [%s] wrote batch of %d metrics in %s\n",
-			ro.Name, nMetrics, elapsed)
+		for _, m := range metrics {
+			m.Accept()
+		}
+		ro.log.Debugf("Wrote batch of %d metrics in %s", nMetrics, elapsed)
 		ro.MetricsWritten.Incr(int64(nMetrics))
 		ro.WriteTime.Incr(elapsed.Nanoseconds())
+		ro.LastWrite.Set(time.Now().UnixNano())
+		ro.retry.success()
+		ro.updateCircuitState()
+		return nil
+	}
+
+	ro.WriteErrors.Incr(1)
+	drop := ro.retry.failure(err)
+	ro.updateCircuitState()
+	if drop {
+		// the batch is not returned to the buffer, so the error is only
+		// reported here.
+		ro.log.Errorf("Dropped batch of %d metrics: %s", nMetrics, err)
+		ro.MetricsDropped.Incr(int64(nMetrics))
+		for _, m := range metrics {
+			m.Reject()
+		}
+		if isPermanent(err) {
+			// the retry policy does not hold off writes after a permanent
+			// error, the next batch is written right away.
+			return nil
+		}
+		return errDropped
+	}
+	return err
+}
+
+// updateCircuitState reports the state of the circuit breaker, logging its
+// changes.
+func (ro *RunningOutput) updateCircuitState() {
+	state := ro.retry.State()
+	ro.CircuitState.Set(int64(state))
+	if int(atomic.SwapInt32(&ro.circuitState, int32(state))) == state {
+		return
+	}
+	switch state {
+	case breakerOpen:
+		ro.log.Errorf("Circuit breaker open, holding off writes for %s",
+			ro.retry.config.BreakerTimeout)
+	case breakerHalfOpen:
+		ro.log.Debugf("Circuit breaker half-open, attempting a write")
+	case breakerClosed:
+		ro.log.Infof("Circuit breaker closed, writes resumed")
+	}
+}
+
+// Writing returns the number of metrics of the write in progress, 0 if the
+// output is not being written to.
+func (ro *RunningOutput) Writing() int {
+	return int(atomic.LoadInt32(&ro.writing))
+}
+
+// PersistBuffer gives up writing the buffered metrics at shutdown. They are
+// kept in the disk buffer to be written the next time the output is started,
+// or dropped when the output has no buffer directory. A write in progress is
+// abandoned: its batch is still in the disk buffer, or is counted as dropped.
+// The abandoned write does not use the buffers anymore once it completes. It
+// returns the number of metrics kept and dropped, and of the abandoned write.
+func (ro *RunningOutput) PersistBuffer() (kept int, dropped int, abandoned int) {
+	ro.bufferMu.Lock()
+	defer ro.bufferMu.Unlock()
+	ro.persisted++
+
+	abandoned = ro.Writing()
+	batch := ro.metrics.Batch(ro.metrics.Len())
+	if _, ok := ro.failMetrics.(*buffer.DiskBuffer); ok {
+		ro.failMetrics.Add(batch...)
+		return ro.failMetrics.Len(), 0, abandoned
+	}
+
+	batch = append(ro.failMetrics.Batch(ro.failMetrics.Len()), batch...)
+	for _, m := range batch {
+		m.Reject()
+	}
+	dropped = len(batch) + abandoned
+	ro.MetricsDropped.Incr(int64(dropped))
+	return 0, dropped, abandoned
+}
+
+// Close closes the output and the metric buffer. Metrics remaining in a disk
+// buffer are kept, to be written the next time the output is started. The
+// output is not closed while a write abandoned at shutdown is in progress.
+func (ro *RunningOutput) Close() error {
+	ro.bufferMu.Lock()
+	defer ro.bufferMu.Unlock()
+
+	var err error
+	if ro.Writing() == 0 {
+		err = ro.Output.Close()
+	}
+	// the metrics of the in-memory buffers are lost.
+	for _, m := range ro.metrics.Batch(ro.metrics.Len()) {
+		m.Reject()
+	}
+	if b, ok := ro.failMetrics.(*buffer.Buffer); ok {
+		for _, m := range b.Batch(b.Len()) {
+			m.Reject()
+		}
+	}
+	if c, ok := ro.failMetrics.(io.Closer); ok {
+		if cerr := c.Close(); cerr != nil {
+			ro.log.Errorf("Error closing buffer: %s", cerr)
+		}
 	}
 	return err
 }
@@ -184,5 +543,26 @@ func (ro *RunningOutput) write(metrics []telegraf.Metric) error {
 // OutputConfig containing name and filter
 type OutputConfig struct {
 	Name   string
+	Alias  string
 	Filter Filter
+
+	// FlushInterval overrides the agent flush_interval for this output.
+	FlushInterval time.Duration
+	// FlushJitter overrides the agent flush_jitter for this output.
+	FlushJitter time.Duration
+	// FlushWhenFull writes a batch as soon as it is full, without waiting for
+	// the next flush interval.
+	FlushWhenFull bool
+
+	// Retry is the policy applied when writes fail.
+	Retry RetryConfig
+
+	// BufferDirectory, when set, keeps metrics that failed to be written in
+	// segment files in this directory instead of in memory.
+	BufferDirectory string
+	// BufferMaxSize is the maximum size in bytes of the disk buffer.
+	BufferMaxSize int64
+
+	// LogLevel overrides the log level of the agent for the output.
+	LogLevel string
 }
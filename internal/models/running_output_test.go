@@ -1,11 +1,16 @@
 package models
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/stretchr/testify/assert"
@@ -248,11 +253,11 @@ func TestRunningOutputDefault(t *testing.T) {
 	assert.Len(t, m.Metrics(), 10)
 }
 
-// Test that running output doesn't flush until it's full when
-// FlushBufferWhenFull is set.
+// Test that running output signals a full batch when FlushWhenFull is set.
 func TestRunningOutputFlushWhenFull(t *testing.T) {
 	conf := &OutputConfig{
-		Filter: Filter{},
+		Filter:        Filter{},
+		FlushWhenFull: true,
 	}
 
 	m := &mockOutput{}
@@ -262,12 +267,16 @@ func TestRunningOutputFlushWhenFull(t *testing.T) {
 	for _, metric := range first5 {
 		ro.AddMetric(metric)
 	}
-	// no flush yet
-	assert.Len(t, m.Metrics(), 0)
+	// no batch ready yet
+	assert.Len(t, ro.BatchReady, 0)
 
 	// add one more metric
 	ro.AddMetric(next5[0])
-	// now it flushed
+	// now a batch is ready, but AddMetric never writes
+	assert.Len(t, ro.BatchReady, 1)
+	assert.Len(t, m.Metrics(), 0)
+	<-ro.BatchReady
+	require.NoError(t, ro.Write())
 	assert.Len(t, m.Metrics(), 6)
 
 	// add one more metric and write it manually
@@ -277,8 +286,7 @@ func TestRunningOutputFlushWhenFull(t *testing.T) {
 	assert.Len(t, m.Metrics(), 7)
 }
 
-// Test that running output doesn't flush until it's full when
-// FlushBufferWhenFull is set, twice.
+// Test that full batches are kept in order for the next write, twice.
 func TestRunningOutputMultiFlushWhenFull(t *testing.T) {
 	conf := &OutputConfig{
 		Filter: Filter{},
@@ -294,8 +302,12 @@ func TestRunningOutputMultiFlushWhenFull(t *testing.T) {
 	for _, metric := range next5 {
 		ro.AddMetric(metric)
 	}
-	// flushed twice
-	assert.Len(t, m.Metrics(), 8)
+	// FlushWhenFull is not set
+	assert.Len(t, ro.BatchReady, 0)
+	assert.Len(t, m.Metrics(), 0)
+
+	require.NoError(t, ro.Write())
+	assert.Equal(t, append(first5, next5...), m.Metrics())
 }
 
 func TestRunningOutputWriteFail(t *testing.T) {
@@ -554,3 +566,448 @@ func (m *perfOutput) Write(metrics []telegraf.Metric) error {
 	}
 	return nil
 }
+
+// Verify that metrics that failed to be written are kept in the disk buffer
+// when the output is closed, and written by the next running output.
+func TestRunningOutputDiskBufferReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-output")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := &OutputConfig{
+		Filter:          Filter{},
+		BufferDirectory: dir,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 100, 1000)
+	require.NoError(t, ro.Connect())
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	require.NoError(t, ro.Close())
+
+	m = &mockOutput{}
+	ro = NewRunningOutput("test", m, conf, 100, 1000)
+	require.NoError(t, ro.Connect())
+	defer ro.Close()
+	require.NoError(t, ro.Write())
+
+	require.Len(t, m.Metrics(), 5)
+	assert.Equal(t, "metric1", m.Metrics()[0].Name())
+	assert.Equal(t, "metric5", m.Metrics()[4].Name())
+}
+
+// Verify that failed writes leave the disk buffer as it is, instead of
+// reading the batches back and writing them again on every flush.
+func TestRunningOutputDiskBufferFailedWrites(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-output")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := &OutputConfig{
+		Filter:          Filter{},
+		BufferDirectory: dir,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 2, 10)
+	require.NoError(t, ro.Connect())
+	defer ro.Close()
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+
+	size := func() int64 {
+		var total int64
+		files, err := ioutil.ReadDir(dir)
+		require.NoError(t, err)
+		for _, f := range files {
+			total += f.Size()
+		}
+		return total
+	}
+	before := size()
+	require.Error(t, ro.Write())
+	require.Error(t, ro.Write())
+	assert.Equal(t, before, size())
+	assert.Equal(t, 5, ro.failMetrics.Len())
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 5)
+	assert.Equal(t, "metric1", m.Metrics()[0].Name())
+	assert.Equal(t, 0, ro.failMetrics.Len())
+}
+
+func TestRunningOutputPersistBuffer(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 2, 10)
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+
+	kept, dropped, _ := ro.PersistBuffer()
+	assert.Equal(t, 0, kept)
+	assert.Equal(t, 5, dropped)
+	assert.Equal(t, int64(5), ro.MetricsDropped.Get())
+	require.NoError(t, ro.Close())
+}
+
+func TestRunningOutputPersistDiskBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-output")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := &OutputConfig{
+		Filter:          Filter{},
+		BufferDirectory: dir,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 2, 10)
+	require.NoError(t, ro.Connect())
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+
+	// the metrics of the incomplete batch are persisted too
+	kept, dropped, _ := ro.PersistBuffer()
+	assert.Equal(t, 5, kept)
+	assert.Equal(t, 0, dropped)
+	require.NoError(t, ro.Close())
+
+	m = &mockOutput{}
+	ro = NewRunningOutput("test", m, conf, 100, 1000)
+	require.NoError(t, ro.Connect())
+	defer ro.Close()
+	require.NoError(t, ro.Write())
+	assert.Len(t, m.Metrics(), 5)
+}
+
+// blockingOutput fails its writes once release is closed.
+type blockingOutput struct {
+	mockOutput
+	writing chan struct{}
+	release chan struct{}
+}
+
+func (m *blockingOutput) Write(metrics []telegraf.Metric) error {
+	close(m.writing)
+	<-m.release
+	return fmt.Errorf("Failed Write!")
+}
+
+// abandonWrite starts a write blocked in the output, and gives up on it.
+func abandonWrite(t *testing.T, ro *RunningOutput, m *blockingOutput) (chan error, int, int) {
+	written := make(chan error)
+	go func() {
+		written <- ro.Write()
+	}()
+	<-m.writing
+	kept, dropped, abandoned := ro.PersistBuffer()
+	assert.Equal(t, 5, abandoned)
+	return written, kept, dropped
+}
+
+// Verify that the batch of a write abandoned at shutdown stays in the disk
+// buffer.
+func TestRunningOutputAbandonedWriteDiskBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-output")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := &OutputConfig{
+		Filter:          Filter{},
+		BufferDirectory: dir,
+	}
+	m := &blockingOutput{
+		writing: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	ro := NewRunningOutput("test", m, conf, 5, 100)
+	require.NoError(t, ro.Connect())
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	ro.AddMetric(next5[0])
+
+	written, kept, dropped := abandonWrite(t, ro, m)
+	assert.Equal(t, 6, kept)
+	assert.Equal(t, 0, dropped)
+	require.NoError(t, ro.Close())
+
+	// the failed write completes after the buffer is closed
+	close(m.release)
+	require.NoError(t, <-written)
+
+	mo := &mockOutput{}
+	ro = NewRunningOutput("test", mo, conf, 100, 1000)
+	require.NoError(t, ro.Connect())
+	defer ro.Close()
+	require.NoError(t, ro.Write())
+	require.Len(t, mo.Metrics(), 6)
+	assert.Equal(t, "metric1", mo.Metrics()[0].Name())
+}
+
+// Verify that the batch of a write abandoned at shutdown is counted as
+// dropped, and not added back to the buffer.
+func TestRunningOutputAbandonedWrite(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+	m := &blockingOutput{
+		writing: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	ro := NewRunningOutput("test", m, conf, 5, 100)
+	ro.MetricsDropped.Set(0)
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	ro.AddMetric(next5[0])
+
+	written, kept, dropped := abandonWrite(t, ro, m)
+	assert.Equal(t, 0, kept)
+	assert.Equal(t, 6, dropped)
+	assert.Equal(t, int64(6), ro.MetricsDropped.Get())
+
+	close(m.release)
+	require.NoError(t, <-written)
+	assert.Equal(t, 0, ro.failMetrics.Len())
+	require.NoError(t, ro.Close())
+}
+
+type permanentFailOutput struct {
+	mockOutput
+}
+
+func (m *permanentFailOutput) Write(metrics []telegraf.Metric) error {
+	return permanentError{}
+}
+
+// Verify that batches failing with a permanent error are not retried.
+func TestRunningOutputPermanentError(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	m := &permanentFailOutput{}
+	ro := NewRunningOutput("test", m, conf, 5, 100)
+	ro.MetricsDropped.Set(0)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	for _, metric := range next5 {
+		ro.AddMetric(metric)
+	}
+
+	require.NoError(t, ro.Write())
+	assert.Equal(t, int64(10), ro.MetricsDropped.Get())
+	assert.Equal(t, 0, ro.failMetrics.Len())
+}
+
+// Verify that the batches after one dropped for max attempts wait for the
+// next write.
+func TestRunningOutputMaxAttempts(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+		Retry: RetryConfig{
+			MaxAttempts: 2,
+		},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 5, 100)
+	ro.MetricsDropped.Set(0)
+	ro.WriteErrors.Set(0)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	for _, metric := range next5 {
+		ro.AddMetric(metric)
+	}
+
+	require.Error(t, ro.Write())
+	require.NoError(t, ro.Write())
+	assert.Equal(t, int64(5), ro.MetricsDropped.Get())
+	assert.Equal(t, int64(2), ro.WriteErrors.Get())
+	assert.Equal(t, 5, ro.failMetrics.Len())
+	assert.Equal(t, 0, ro.metrics.Len())
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	assert.Len(t, m.Metrics(), 5)
+}
+
+// Verify that the circuit breaker skips writes to a failing output.
+func TestRunningOutputCircuitBreaker(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+		Retry: RetryConfig{
+			BreakerThreshold: 1,
+			BreakerTimeout:   time.Hour,
+		},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 5, 100)
+	ro.WritesSkipped.Set(0)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	assert.Equal(t, int64(breakerOpen), ro.CircuitState.Get())
+
+	// skipped writes are not errors
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	require.NoError(t, ro.Write())
+	assert.Equal(t, int64(2), ro.WritesSkipped.Get())
+	assert.Len(t, m.Metrics(), 0)
+	assert.Equal(t, 5, ro.failMetrics.Len())
+}
+
+type contextOutput struct {
+	mockOutput
+}
+
+func (m *contextOutput) WriteContext(
+	ctx context.Context,
+	metrics []telegraf.Metric,
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return m.mockOutput.Write(metrics)
+}
+
+// Verify that the context is passed to context outputs, and that the metrics
+// of a cancelled write stay buffered.
+func TestRunningOutputWriteContext(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	m := &contextOutput{}
+	ro := NewRunningOutput("test", m, conf, 5, 100)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, ro.WriteContext(ctx))
+	assert.Len(t, m.Metrics(), 0)
+	assert.Equal(t, 5, ro.failMetrics.Len())
+
+	require.NoError(t, ro.WriteContext(context.Background()))
+	assert.Len(t, m.Metrics(), 5)
+	assert.Equal(t, 0, ro.failMetrics.Len())
+}
+
+// trackMetrics returns copies of the metrics tracked one by one, and the
+// delivery outcomes by tracking id.
+func trackMetrics(
+	metrics []telegraf.Metric,
+) ([]telegraf.Metric, map[telegraf.TrackingID]bool) {
+	var mu sync.Mutex
+	delivered := make(map[telegraf.TrackingID]bool)
+	var tracked []telegraf.Metric
+	for _, m := range metrics {
+		group, _ := metric.WithGroupTracking([]telegraf.Metric{m.Copy()},
+			func(info telegraf.DeliveryInfo) {
+				mu.Lock()
+				delivered[info.ID()] = info.Delivered()
+				mu.Unlock()
+			})
+		tracked = append(tracked, group...)
+	}
+	return tracked, delivered
+}
+
+// Verify that written metrics are accepted, and that filtered metrics are
+// dropped.
+func TestRunningOutputTracking(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{
+			NamePass:   []string{"metric1", "metric2", "metric3", "metric4"},
+			TagExclude: []string{"tag1"},
+		},
+	}
+	assert.NoError(t, conf.Filter.Compile())
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+
+	tracked, delivered := trackMetrics(first5)
+	for _, metric := range tracked {
+		ro.AddMetric(metric)
+	}
+	assert.Len(t, delivered, 1)
+
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 4)
+	assert.False(t, m.Metrics()[0].HasTag("tag1"))
+	require.Len(t, delivered, 5)
+	for _, ok := range delivered {
+		assert.True(t, ok)
+	}
+}
+
+// Verify that the metrics lost from the buffers are rejected.
+func TestRunningOutputTrackingRejected(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 2, 4)
+
+	tracked, delivered := trackMetrics(append(first5, next5...))
+	for _, metric := range tracked[:6] {
+		ro.AddMetric(metric)
+	}
+	// the oldest batch is dropped from the full buffer.
+	assert.Len(t, delivered, 2)
+
+	require.Error(t, ro.Write())
+	for _, metric := range tracked[6:] {
+		ro.AddMetric(metric)
+	}
+	require.NoError(t, ro.Close())
+	require.Len(t, delivered, 10)
+	for _, ok := range delivered {
+		assert.False(t, ok)
+	}
+}
+
+func TestRunningOutputAlias(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+		Alias:  "primary",
+	}
+
+	ro := NewRunningOutput("influxdb", &mockOutput{}, conf, 1000, 10000)
+	assert.Equal(t, "influxdb::primary", ro.LogName())
+	assert.Equal(t,
+		map[string]string{"output": "influxdb", "alias": "primary"},
+		ro.MetricsWritten.Tags())
+}
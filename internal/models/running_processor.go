@@ -4,12 +4,31 @@ import (
 	"github.com/influxdata/telegraf"
 )
 
+// Default number of metrics that can be queued in front of each processor
+// worker before the processor blocks the inputs.
+const DEFAULT_PROCESSOR_QUEUE_SIZE = 100
+
 type RunningProcessor struct {
 	Name      string
 	Processor telegraf.Processor
 	Config    *ProcessorConfig
 }
 
+// NewRunningProcessor returns the RunningProcessor of processor, giving the
+// processor its logger.
+func NewRunningProcessor(
+	name string,
+	processor telegraf.Processor,
+	conf *ProcessorConfig,
+) *RunningProcessor {
+	newLogger(processor, "processors."+name, conf.Alias, conf.LogLevel)
+	return &RunningProcessor{
+		Name:      name,
+		Processor: processor,
+		Config:    conf,
+	}
+}
+
 type RunningProcessors []*RunningProcessor
 
 func (rp RunningProcessors) Len() int           { return len(rp) }
@@ -19,8 +38,31 @@ func (rp RunningProcessors) Less(i, j int) bool { return rp[i].Config.Order < rp
 // FilterConfig containing a name and filter
 type ProcessorConfig struct {
 	Name   string
+	Alias  string
 	Order  int64
 	Filter Filter
+
+	// LogLevel overrides the log level of the agent for the processor.
+	LogLevel string
+}
+
+// LogName returns the name of the processor in log messages, including its
+// alias.
+func (rp *RunningProcessor) LogName() string {
+	return logName(rp.Name, rp.Config.Alias)
+}
+
+// StatTags returns the selfstat tags identifying the processor.
+func (rp *RunningProcessor) StatTags() map[string]string {
+	return statTags("processor", rp.Name, rp.Config.Alias)
+}
+
+// Init initializes processors implementing telegraf.InitProcessor.
+func (rp *RunningProcessor) Init() error {
+	if p, ok := rp.Processor.(telegraf.InitProcessor); ok {
+		return p.Init(rp.StatTags())
+	}
+	return nil
 }
 
 func (rp *RunningProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
@@ -37,8 +79,22 @@ func (rp *RunningProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
 		}
 		// This metric should pass through the filter, so call the filter Apply
 		// function and append results to the output slice.
-		ret = append(ret, rp.Processor.Apply(metric)...)
+		out := rp.Processor.Apply(metric)
+		if !containsMetric(out, metric) {
+			// the processor removed the metric, or replaced it by new ones.
+			metric.Drop()
+		}
+		ret = append(ret, out...)
 	}
 
 	return ret
 }
+
+func containsMetric(metrics []telegraf.Metric, m telegraf.Metric) bool {
+	for _, other := range metrics {
+		if other == m {
+			return true
+		}
+	}
+	return false
+}
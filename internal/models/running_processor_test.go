@@ -1,6 +1,7 @@
 package models
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/influxdata/telegraf"
@@ -115,3 +116,24 @@ func TestRunningProcessor_DroppedMetric(t *testing.T) {
 	}
 	assert.Equal(t, expectedNames, actualNames)
 }
+
+type initProcessor struct {
+	TestProcessor
+	tags map[string]string
+	err  error
+}
+
+func (p *initProcessor) Init(tags map[string]string) error {
+	p.tags = tags
+	return p.err
+}
+
+func TestRunningProcessorInit(t *testing.T) {
+	p := &initProcessor{err: errors.New("invalid")}
+	rp := NewRunningProcessor("test", p, &ProcessorConfig{Alias: "a"})
+	assert.Error(t, rp.Init())
+	assert.Equal(t, map[string]string{"processor": "test", "alias": "a"}, p.tags)
+
+	// processors without Init are left alone
+	assert.NoError(t, NewTestRunningProcessor().Init())
+}
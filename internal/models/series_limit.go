@@ -0,0 +1,142 @@
+package models
+
+import (
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+const (
+	// Default time after which a series that was not seen again is forgotten.
+	DEFAULT_SERIES_WINDOW = time.Hour
+
+	// Value given to the rewritten tags of the series over the limit.
+	SERIES_OTHER_VALUE = "other"
+)
+
+// Actions applied to the new series over the limit.
+const (
+	SeriesActionDrop    = "drop"
+	SeriesActionRewrite = "rewrite"
+)
+
+// SeriesLimitConfig limits the number of series created by an input.
+type SeriesLimitConfig struct {
+	// MaxSeries is the number of series the input may create within
+	// Window. 0 disables the limit.
+	MaxSeries int
+
+	// Window is how long a series is counted after it was last seen.
+	Window time.Duration
+
+	// Action is what happens to the metrics of new series once MaxSeries is
+	// reached: SeriesActionDrop, the default, or SeriesActionRewrite.
+	Action string
+
+	// RewriteTags are the tags whose value is replaced by SERIES_OTHER_VALUE
+	// with SeriesActionRewrite.
+	RewriteTags []string
+}
+
+// Results of seriesLimiter.limit.
+const (
+	seriesAccepted = iota
+	seriesDropped
+	seriesRewritten
+)
+
+// seriesLimiter tracks the series of the metrics by their HashID in a sliding
+// window, and drops or rewrites the metrics of new series once the limit is
+// reached.
+type seriesLimiter struct {
+	config SeriesLimitConfig
+
+	// seen is the last time each series was seen.
+	seen map[uint64]time.Time
+	// nextSweep is the earliest time the expired series are removed.
+	nextSweep time.Time
+	// limited is true while new series are refused.
+	limited bool
+
+	// now is replaced in tests.
+	now func() time.Time
+
+	mu sync.Mutex
+}
+
+func newSeriesLimiter(config SeriesLimitConfig) *seriesLimiter {
+	if config.Window == 0 {
+		config.Window = DEFAULT_SERIES_WINDOW
+	}
+	if config.Action == "" {
+		config.Action = SeriesActionDrop
+	}
+	return &seriesLimiter{
+		config: config,
+		seen:   make(map[uint64]time.Time),
+		now:    time.Now,
+	}
+}
+
+// limit records the series of the metric. Once the limit is reached the
+// metric of a new series is either rewritten in place or must be dropped. The
+// second result is true for the first metric refused since the limit was
+// reached.
+func (l *seriesLimiter) limit(m telegraf.Metric) (int, bool) {
+	id := m.HashID()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if _, ok := l.seen[id]; ok || len(l.seen) < l.config.MaxSeries {
+		l.seen[id] = now
+		return seriesAccepted, false
+	}
+
+	// The series expire one by one, sweeping them all on every new series
+	// would be too expensive when an input keeps creating them.
+	if !now.Before(l.nextSweep) {
+		l.sweep(now)
+		l.nextSweep = now.Add(l.config.Window / 10)
+		if len(l.seen) < l.config.MaxSeries {
+			l.limited = false
+			l.seen[id] = now
+			return seriesAccepted, false
+		}
+	}
+
+	first := !l.limited
+	l.limited = true
+
+	if l.config.Action == SeriesActionRewrite {
+		var rewritten bool
+		for _, key := range l.config.RewriteTags {
+			if m.HasTag(key) {
+				m.AddTag(key, SERIES_OTHER_VALUE)
+				rewritten = true
+			}
+		}
+		if rewritten {
+			return seriesRewritten, first
+		}
+	}
+	return seriesDropped, first
+}
+
+// sweep forgets the series not seen within the window.
+func (l *seriesLimiter) sweep(now time.Time) {
+	for id, t := range l.seen {
+		if now.Sub(t) >= l.config.Window {
+			delete(l.seen, id)
+		}
+	}
+}
+
+// Len returns the number of series tracked.
+func (l *seriesLimiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.seen)
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSeriesLimiter(config SeriesLimitConfig) (*seriesLimiter, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	l := newSeriesLimiter(config)
+	l.now = clock.now
+	return l, clock
+}
+
+func seriesMetric(t *testing.T, id string) telegraf.Metric {
+	m, err := metric.New("requests",
+		map[string]string{"host": "a", "request_id": id},
+		map[string]interface{}{"value": 1},
+		time.Unix(0, 0))
+	require.NoError(t, err)
+	return m
+}
+
+func TestSeriesLimiterDrop(t *testing.T) {
+	l, _ := newTestSeriesLimiter(SeriesLimitConfig{MaxSeries: 2})
+
+	result, _ := l.limit(seriesMetric(t, "1"))
+	assert.Equal(t, seriesAccepted, result)
+	result, _ = l.limit(seriesMetric(t, "2"))
+	assert.Equal(t, seriesAccepted, result)
+
+	result, first := l.limit(seriesMetric(t, "3"))
+	assert.Equal(t, seriesDropped, result)
+	assert.True(t, first)
+	result, first = l.limit(seriesMetric(t, "4"))
+	assert.Equal(t, seriesDropped, result)
+	assert.False(t, first)
+
+	// Known series are still accepted.
+	result, _ = l.limit(seriesMetric(t, "1"))
+	assert.Equal(t, seriesAccepted, result)
+	assert.Equal(t, 2, l.Len())
+}
+
+func TestSeriesLimiterRewrite(t *testing.T) {
+	l, _ := newTestSeriesLimiter(SeriesLimitConfig{
+		MaxSeries:   1,
+		Action:      SeriesActionRewrite,
+		RewriteTags: []string{"request_id"},
+	})
+
+	m := seriesMetric(t, "1")
+	result, _ := l.limit(m)
+	assert.Equal(t, seriesAccepted, result)
+
+	m = seriesMetric(t, "2")
+	result, _ = l.limit(m)
+	assert.Equal(t, seriesRewritten, result)
+	assert.Equal(t, map[string]string{"host": "a", "request_id": "other"}, m.Tags())
+	assert.NotEqual(t, seriesMetric(t, "2").HashID(), m.HashID())
+
+	// Without any of the rewritten tags the metric is dropped.
+	m, err := metric.New("requests",
+		map[string]string{"host": "b"},
+		map[string]interface{}{"value": 1},
+		time.Unix(0, 0))
+	require.NoError(t, err)
+	result, _ = l.limit(m)
+	assert.Equal(t, seriesDropped, result)
+}
+
+func TestSeriesLimiterWindow(t *testing.T) {
+	l, clock := newTestSeriesLimiter(SeriesLimitConfig{
+		MaxSeries: 2,
+		Window:    time.Minute,
+	})
+
+	l.limit(seriesMetric(t, "1"))
+	clock.t = clock.t.Add(30 * time.Second)
+	l.limit(seriesMetric(t, "2"))
+
+	result, first := l.limit(seriesMetric(t, "3"))
+	assert.Equal(t, seriesDropped, result)
+	assert.True(t, first)
+
+	clock.t = clock.t.Add(29 * time.Second)
+	result, _ = l.limit(seriesMetric(t, "3"))
+	assert.Equal(t, seriesDropped, result)
+
+	// The first series has expired, but the expired series are swept at most
+	// every tenth of the window.
+	clock.t = clock.t.Add(2 * time.Second)
+	result, first = l.limit(seriesMetric(t, "3"))
+	assert.Equal(t, seriesDropped, result)
+	assert.False(t, first)
+
+	clock.t = clock.t.Add(4 * time.Second)
+	result, _ = l.limit(seriesMetric(t, "3"))
+	assert.Equal(t, seriesAccepted, result)
+	assert.Equal(t, 2, l.Len())
+
+	result, first = l.limit(seriesMetric(t, "4"))
+	assert.Equal(t, seriesDropped, result)
+	assert.True(t, first)
+}
@@ -0,0 +1,40 @@
+package internal
+
+import (
+	"strings"
+	"sync"
+)
+
+// minSecretLength is the length under which secrets are not redacted, as
+// hiding them would also hide unrelated text.
+const minSecretLength = 4
+
+var (
+	secretsMu sync.RWMutex
+	secrets   []string
+)
+
+// AddSecret registers a secret value so that Redact hides it.
+func AddSecret(secret string) {
+	if len(secret) < minSecretLength {
+		return
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, s := range secrets {
+		if s == secret {
+			return
+		}
+	}
+	secrets = append(secrets, secret)
+}
+
+// Redact returns s with every registered secret replaced by "****".
+func Redact(s string) string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	for _, secret := range secrets {
+		s = strings.Replace(s, secret, "****", -1)
+	}
+	return s
+}
@@ -0,0 +1,233 @@
+// Package rotate provides a file writer rotating its file by size and age.
+package rotate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// archiveTimeFormat is the layout of the time in the names of the archives,
+// which sort in the order they were rotated.
+const archiveTimeFormat = "2006-01-02T15-04-05.000000000"
+
+// FileWriter is an io.WriteCloser writing to a file, which is rotated when it
+// grows larger than MaxSize or is older than Interval. The rotated files are
+// renamed with the time of the rotation, eg. telegraf.log is archived as
+// telegraf.2018-01-02T15-04-05.000000000.log.
+type FileWriter struct {
+	// Filename is the path of the file.
+	Filename string
+	// Interval rotates the file when it has been open for longer, disabled
+	// when zero.
+	Interval time.Duration
+	// MaxSize rotates the file when a write would make it larger, in bytes.
+	// Disabled when zero.
+	MaxSize int64
+	// MaxAge removes the archives older than this, disabled when zero.
+	MaxAge time.Duration
+	// MaxArchives is the number of archives kept, all of them when zero.
+	MaxArchives int
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+	// closed is set by Close, the file is otherwise opened again by Write
+	// after a failed rotation.
+	closed bool
+
+	// now returns the current time, replaced by the tests.
+	now func() time.Time
+}
+
+// NewFileWriter opens the file, creating it if needed, and returns its
+// writer.
+func NewFileWriter(
+	filename string,
+	interval time.Duration,
+	maxSize int64,
+	maxAge time.Duration,
+	maxArchives int,
+) (*FileWriter, error) {
+	w := &FileWriter{
+		Filename:    filename,
+		Interval:    interval,
+		MaxSize:     maxSize,
+		MaxAge:      maxAge,
+		MaxArchives: maxArchives,
+		now:         time.Now,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// open opens the file in append mode. A file already present keeps counting
+// towards the size limit, but not towards the interval.
+func (w *FileWriter) open() error {
+	f, err := os.OpenFile(w.Filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file = f
+	w.size = info.Size()
+	w.opened = w.now()
+	return nil
+}
+
+// Write writes p to the file, rotating it first if needed.
+func (w *FileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	if w.needsRotation(int64(len(p))) {
+		// the file is kept when it cannot be rotated, the write is not
+		// lost.
+		if err := w.rotate(); err != nil && w.file == nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *FileWriter) needsRotation(n int64) bool {
+	if w.Interval > 0 && w.now().Sub(w.opened) >= w.Interval {
+		return true
+	}
+	// a message larger than MaxSize is written to an empty file.
+	return w.MaxSize > 0 && w.size > 0 && w.size+n > w.MaxSize
+}
+
+// Rotate archives the file and opens a new one.
+func (w *FileWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	return w.rotate()
+}
+
+// rotate archives the file and opens a new one. The original file is opened
+// again when it cannot be archived.
+func (w *FileWriter) rotate() error {
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	if err == nil {
+		err = os.Rename(w.Filename, w.archiveName())
+	}
+	if oerr := w.open(); oerr != nil {
+		return oerr
+	}
+	if err != nil {
+		return err
+	}
+	return w.removeArchives()
+}
+
+// Reopen closes and opens the file again, to write to a new file once the
+// current one has been moved by an external tool such as logrotate.
+func (w *FileWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+	return w.open()
+}
+
+// Close closes the file.
+func (w *FileWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// archiveName returns the path the file is renamed to when rotated.
+func (w *FileWriter) archiveName() string {
+	ext := filepath.Ext(w.Filename)
+	base := strings.TrimSuffix(w.Filename, ext)
+	return fmt.Sprintf("%s.%s%s", base, w.now().Format(archiveTimeFormat), ext)
+}
+
+// archives returns the archives of the file, from the oldest to the newest.
+func (w *FileWriter) archives() ([]string, error) {
+	ext := filepath.Ext(w.Filename)
+	base := strings.TrimSuffix(w.Filename, ext)
+	matches, err := filepath.Glob(base + ".*" + ext)
+	if err != nil {
+		return nil, err
+	}
+
+	archives := make([]string, 0, len(matches))
+	for _, m := range matches {
+		ts := strings.TrimSuffix(strings.TrimPrefix(m, base+"."), ext)
+		if _, err := time.Parse(archiveTimeFormat, ts); err == nil {
+			archives = append(archives, m)
+		}
+	}
+	sort.Strings(archives)
+	return archives, nil
+}
+
+// removeArchives removes the archives older than MaxAge, and the oldest
+// archives in excess of MaxArchives.
+func (w *FileWriter) removeArchives() error {
+	if w.MaxAge <= 0 && w.MaxArchives <= 0 {
+		return nil
+	}
+	archives, err := w.archives()
+	if err != nil {
+		return err
+	}
+
+	for i, path := range archives {
+		remove := w.MaxArchives > 0 && len(archives)-i > w.MaxArchives
+		if !remove && w.MaxAge > 0 {
+			info, err := os.Stat(path)
+			remove = err == nil && w.now().Sub(info.ModTime()) > w.MaxAge
+		}
+		if remove {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package rotate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func newTestWriter(t *testing.T, dir string, interval time.Duration,
+	maxSize int64, maxAge time.Duration, maxArchives int,
+) (*FileWriter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2018, 1, 2, 15, 4, 5, 0, time.UTC)}
+	w := &FileWriter{
+		Filename:    filepath.Join(dir, "telegraf.log"),
+		Interval:    interval,
+		MaxSize:     maxSize,
+		MaxAge:      maxAge,
+		MaxArchives: maxArchives,
+		now:         clock.Now,
+	}
+	require.NoError(t, w.open())
+	return w, clock
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "rotate")
+	require.NoError(t, err)
+	return dir
+}
+
+func readFile(t *testing.T, path string) string {
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestFileWriterNoRotation(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	w, err := NewFileWriter(filepath.Join(dir, "telegraf.log"), 0, 0, 0, 0)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("hello\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	// the existing file is appended to
+	w, err = NewFileWriter(filepath.Join(dir, "telegraf.log"), 0, 0, 0, 0)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("world\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, "hello\nworld\n", readFile(t, filepath.Join(dir, "telegraf.log")))
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	assert.Len(t, files, 1)
+}
+
+func TestFileWriterMaxSize(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	w, clock := newTestWriter(t, dir, 0, 10, 0, 0)
+	defer w.Close()
+
+	w.Write([]byte("12345\n"))
+	clock.now = clock.now.Add(time.Second)
+	w.Write([]byte("67890\n"))
+
+	archives, err := w.archives()
+	require.NoError(t, err)
+	require.Len(t, archives, 1)
+	assert.Equal(t,
+		filepath.Join(dir, "telegraf.2018-01-02T15-04-06.000000000.log"),
+		archives[0])
+	assert.Equal(t, "12345\n", readFile(t, archives[0]))
+	assert.Equal(t, "67890\n", readFile(t, w.Filename))
+}
+
+func TestFileWriterInterval(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	w, clock := newTestWriter(t, dir, time.Hour, 0, 0, 0)
+	defer w.Close()
+
+	w.Write([]byte("first\n"))
+	clock.now = clock.now.Add(30 * time.Minute)
+	w.Write([]byte("second\n"))
+	clock.now = clock.now.Add(30 * time.Minute)
+	w.Write([]byte("third\n"))
+
+	archives, err := w.archives()
+	require.NoError(t, err)
+	require.Len(t, archives, 1)
+	assert.Equal(t, "first\nsecond\n", readFile(t, archives[0]))
+	assert.Equal(t, "third\n", readFile(t, w.Filename))
+}
+
+func TestFileWriterMaxArchives(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	w, clock := newTestWriter(t, dir, 0, 0, 0, 2)
+	defer w.Close()
+
+	for _, msg := range []string{"1\n", "2\n", "3\n", "4\n"} {
+		w.Write([]byte(msg))
+		clock.now = clock.now.Add(time.Second)
+		require.NoError(t, w.Rotate())
+	}
+
+	archives, err := w.archives()
+	require.NoError(t, err)
+	require.Len(t, archives, 2)
+	assert.Equal(t, "3\n", readFile(t, archives[0]))
+	assert.Equal(t, "4\n", readFile(t, archives[1]))
+}
+
+func TestFileWriterMaxAge(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	w, clock := newTestWriter(t, dir, 0, 0, time.Hour, 0)
+	defer w.Close()
+
+	w.Write([]byte("old\n"))
+	require.NoError(t, w.Rotate())
+	archives, err := w.archives()
+	require.NoError(t, err)
+	require.Len(t, archives, 1)
+	old := clock.now.Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(archives[0], old, old))
+
+	clock.now = clock.now.Add(time.Second)
+	w.Write([]byte("new\n"))
+	require.NoError(t, w.Rotate())
+
+	archives, err = w.archives()
+	require.NoError(t, err)
+	require.Len(t, archives, 1)
+	assert.Equal(t, "new\n", readFile(t, archives[0]))
+}
+
+func TestFileWriterReopen(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	w, _ := newTestWriter(t, dir, 0, 0, 0, 0)
+	defer w.Close()
+
+	w.Write([]byte("before\n"))
+	// logrotate moves the file away, then signals telegraf
+	moved := filepath.Join(dir, "telegraf.log.1")
+	require.NoError(t, os.Rename(w.Filename, moved))
+	require.NoError(t, w.Reopen())
+	w.Write([]byte("after\n"))
+
+	assert.Equal(t, "before\n", readFile(t, moved))
+	assert.Equal(t, "after\n", readFile(t, w.Filename))
+}
+
+func TestFileWriterFailedRotation(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	w, _ := newTestWriter(t, dir, 0, 0, 0, 0)
+	defer w.Close()
+
+	w.Write([]byte("before\n"))
+	// a directory in place of the archive makes the rename fail
+	require.NoError(t, os.Mkdir(w.archiveName(), 0755))
+	require.Error(t, w.Rotate())
+
+	// the original file is written to again
+	_, err := w.Write([]byte("after\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "before\nafter\n", readFile(t, w.Filename))
+}
@@ -0,0 +1,27 @@
+package telegraf
+
+// Logger is the logger of a plugin. Its messages carry the name and alias of
+// the plugin.
+//
+// Plugins receive their Logger in an exported field of their struct, which
+// telegraf sets before the plugin is started:
+//
+//   Log telegraf.Logger `toml:"-"`
+type Logger interface {
+	// Errorf logs an error message, formatted like fmt.Printf.
+	Errorf(format string, args ...interface{})
+	// Error logs an error message, formatted like fmt.Print.
+	Error(args ...interface{})
+	// Warnf logs a warning message, formatted like fmt.Printf.
+	Warnf(format string, args ...interface{})
+	// Warn logs a warning message, formatted like fmt.Print.
+	Warn(args ...interface{})
+	// Infof logs an informational message, formatted like fmt.Printf.
+	Infof(format string, args ...interface{})
+	// Info logs an informational message, formatted like fmt.Print.
+	Info(args ...interface{})
+	// Debugf logs a debug message, formatted like fmt.Printf.
+	Debugf(format string, args ...interface{})
+	// Debug logs a debug message, formatted like fmt.Print.
+	Debug(args ...interface{})
+}
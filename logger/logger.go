@@ -1,69 +1,243 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"os"
 	"regexp"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/influxdata/wlog"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/rotate"
 )
 
 var prefixRegex = regexp.MustCompile("^[DIWE]!")
 
-// newTelegrafWriter returns a logging-wrapped writer.
-func newTelegrafWriter(w io.Writer) io.Writer {
-	return &telegrafLog{
-		writer: wlog.NewWriter(w),
+// Level is the severity of a log message. Messages are logged when their
+// level is lower or equal to the level of the logger.
+type Level int
+
+const (
+	LevelError Level = iota
+	LevelWarn
+	LevelInfo
+	LevelDebug
+)
+
+var levelNames = []string{"error", "warn", "info", "debug"}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// prefix returns the prefix of the level in text messages, eg. "E!".
+func (l Level) prefix() string {
+	return strings.ToUpper(levelNames[l][:1]) + "!"
+}
+
+// ParseLevel returns the level named s: "error", "warn", "info" or "debug".
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if strings.ToLower(s) == name {
+			return Level(i), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid log level %q, must be one of %s",
+		s, strings.Join(levelNames, ", "))
+}
+
+// LogConfig is the configuration of the logging output.
+type LogConfig struct {
+	// Debug sets the log level to debug.
+	Debug bool
+	// Quiet sets the log level to error.
+	Quiet bool
+	// Logfile is the file the messages are written to. Empty string is
+	// interpreted as stderr. If there is an error opening the file the logger
+	// will fallback to stderr.
+	Logfile string
+	// Format is "text", the default, or "json" to write each message as a
+	// JSON object.
+	Format string
+	// TimestampFormat is the Go time layout of the timestamps, RFC3339 by
+	// default.
+	TimestampFormat string
+
+	// RotationInterval rotates the logfile when it is older, disabled when
+	// zero.
+	RotationInterval time.Duration
+	// RotationMaxSize rotates the logfile when it grows larger, in bytes.
+	// Disabled when zero.
+	RotationMaxSize int64
+	// RotationMaxAge removes the rotated logfiles older than this, disabled
+	// when zero.
+	RotationMaxAge time.Duration
+	// RotationMaxArchives is the number of rotated logfiles kept, all of them
+	// when zero.
+	RotationMaxArchives int
+}
+
+// output is where the messages of the standard logger and of the plugin
+// loggers are written.
+type output struct {
+	sync.Mutex
+	writer          io.Writer
+	level           Level
+	json            bool
+	timestampFormat string
+}
+
+var out = &output{
+	writer:          os.Stderr,
+	level:           LevelInfo,
+	timestampFormat: time.RFC3339,
+}
+
+// entry is a message in JSON format.
+type entry struct {
+	Time   string `json:"time"`
+	Level  string `json:"level"`
+	Plugin string `json:"plugin,omitempty"`
+	Alias  string `json:"alias,omitempty"`
+	Msg    string `json:"msg"`
+}
+
+// enabled returns true if messages of the level are logged at the level of
+// the output.
+func (o *output) enabled(level Level) bool {
+	o.Lock()
+	defer o.Unlock()
+	return level <= o.level
+}
+
+// write writes the message, with the secrets redacted. The plugin and alias
+// are empty for the messages of the standard logger.
+func (o *output) write(level Level, plugin, alias, msg string) error {
+	msg = internal.Redact(strings.TrimSuffix(msg, "\n"))
+
+	o.Lock()
+	defer o.Unlock()
+	ts := time.Now().UTC().Format(o.timestampFormat)
+
+	var buf bytes.Buffer
+	if o.json {
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		err := enc.Encode(entry{
+			Time:   ts,
+			Level:  level.String(),
+			Plugin: plugin,
+			Alias:  alias,
+			Msg:    msg,
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		buf.WriteString(ts + " " + level.prefix() + " ")
+		if plugin != "" {
+			name := plugin
+			if alias != "" {
+				name += "::" + alias
+			}
+			buf.WriteString("[" + name + "] ")
+		}
+		buf.WriteString(msg + "\n")
 	}
+	_, err := o.writer.Write(buf.Bytes())
+	return err
 }
 
+// telegrafLog is the writer of the standard logger. The level of the messages
+// is given by their "E!", "W!", "I!" or "D!" prefix, and defaults to info.
 type telegrafLog struct {
-	writer io.Writer
+	output *output
+}
+
+// newTelegrafWriter returns a logging-wrapped writer.
+func newTelegrafWriter(o *output) io.Writer {
+	return &telegrafLog{output: o}
 }
 
 func (t *telegrafLog) Write(b []byte) (n int, err error) {
-	var line []byte
-	if !prefixRegex.Match(b) {
-		line = append([]byte(time.Now().UTC().Format(time.RFC3339)+" I! "), b...)
-	} else {
-		line = append([]byte(time.Now().UTC().Format(time.RFC3339)+" "), b...)
+	level := LevelInfo
+	msg := string(b)
+	if prefixRegex.Match(b) {
+		switch b[0] {
+		case 'E':
+			level = LevelError
+		case 'W':
+			level = LevelWarn
+		case 'D':
+			level = LevelDebug
+		}
+		msg = strings.TrimPrefix(msg[2:], " ")
 	}
-	return t.writer.Write(line)
+	if !t.output.enabled(level) {
+		return len(b), nil
+	}
+	if err := t.output.write(level, "", "", msg); err != nil {
+		return 0, err
+	}
+	return len(b), nil
 }
 
 // SetupLogging configures the logging output.
-//   debug   will set the log level to DEBUG
-//   quiet   will set the log level to ERROR
-//   logfile will direct the logging output to a file. Empty string is
+//   Debug   will set the log level to DEBUG
+//   Quiet   will set the log level to ERROR
+//   Logfile will direct the logging output to a file. Empty string is
 //           interpreted as stderr. If there is an error opening the file the
 //           logger will fallback to stderr.
-func SetupLogging(debug, quiet bool, logfile string) {
+// The logfile opened by a previous call is closed.
+func SetupLogging(config LogConfig) {
 	log.SetFlags(0)
-	if debug {
-		wlog.SetLevel(wlog.DEBUG)
-	}
-	if quiet {
-		wlog.SetLevel(wlog.ERROR)
-	}
 
-	var oFile *os.File
-	if logfile != "" {
-		if _, err := os.Stat(logfile); os.IsNotExist(err) {
-			if oFile, err = os.Create(logfile); err != nil {
-				log.Printf("E! Unable to create %s (%s), using stderr", logfile, err)
-				oFile = os.Stderr
-			}
+	var writer io.Writer = os.Stderr
+	if config.Logfile != "" {
+		w, err := rotate.NewFileWriter(config.Logfile,
+			config.RotationInterval, config.RotationMaxSize,
+			config.RotationMaxAge, config.RotationMaxArchives)
+		if err != nil {
+			log.Printf("E! Unable to open %s (%s), using stderr", config.Logfile, err)
 		} else {
-			if oFile, err = os.OpenFile(logfile, os.O_APPEND|os.O_WRONLY, os.ModeAppend); err != nil {
-				log.Printf("E! Unable to append to %s (%s), using stderr", logfile, err)
-				oFile = os.Stderr
-			}
+			writer = w
 		}
-	} else {
-		oFile = os.Stderr
 	}
 
-	log.SetOutput(newTelegrafWriter(oFile))
+	out.Lock()
+	if f, ok := out.writer.(*rotate.FileWriter); ok {
+		f.Close()
+	}
+	out.writer = writer
+	out.level = LevelInfo
+	if config.Debug {
+		out.level = LevelDebug
+	}
+	if config.Quiet {
+		out.level = LevelError
+	}
+	out.json = config.Format == "json"
+	out.timestampFormat = time.RFC3339
+	if config.TimestampFormat != "" {
+		out.timestampFormat = config.TimestampFormat
+	}
+	out.Unlock()
+
+	log.SetOutput(newTelegrafWriter(out))
+}
+
+// Reopen reopens the logfile, to be called once the logfile has been moved by
+// an external tool such as logrotate. It does nothing when logging to stderr.
+func Reopen() error {
+	out.Lock()
+	defer out.Unlock()
+	if f, ok := out.writer.(*rotate.FileWriter); ok {
+		return f.Reopen()
+	}
+	return nil
 }
@@ -5,8 +5,12 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf/internal"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -15,7 +19,7 @@ func TestWriteLogToFile(t *testing.T) {
 	assert.NoError(t, err)
 	defer func() { os.Remove(tmpfile.Name()) }()
 
-	SetupLogging(false, false, tmpfile.Name())
+	SetupLogging(LogConfig{Logfile: tmpfile.Name()})
 	log.Printf("I! TEST")
 	log.Printf("D! TEST") // <- should be ignored
 
@@ -29,7 +33,7 @@ func TestDebugWriteLogToFile(t *testing.T) {
 	assert.NoError(t, err)
 	defer func() { os.Remove(tmpfile.Name()) }()
 
-	SetupLogging(true, false, tmpfile.Name())
+	SetupLogging(LogConfig{Debug: true, Logfile: tmpfile.Name()})
 	log.Printf("D! TEST")
 
 	f, err := ioutil.ReadFile(tmpfile.Name())
@@ -42,7 +46,7 @@ func TestErrorWriteLogToFile(t *testing.T) {
 	assert.NoError(t, err)
 	defer func() { os.Remove(tmpfile.Name()) }()
 
-	SetupLogging(false, true, tmpfile.Name())
+	SetupLogging(LogConfig{Quiet: true, Logfile: tmpfile.Name()})
 	log.Printf("E! TEST")
 	log.Printf("I! TEST") // <- should be ignored
 
@@ -56,7 +60,7 @@ func TestAddDefaultLogLevel(t *testing.T) {
 	assert.NoError(t, err)
 	defer func() { os.Remove(tmpfile.Name()) }()
 
-	SetupLogging(true, false, tmpfile.Name())
+	SetupLogging(LogConfig{Debug: true, Logfile: tmpfile.Name()})
 	log.Printf("TEST")
 
 	f, err := ioutil.ReadFile(tmpfile.Name())
@@ -64,10 +68,99 @@ func TestAddDefaultLogLevel(t *testing.T) {
 	assert.Equal(t, f[19:], []byte("Z I! TEST\n"))
 }
 
+func TestRedactSecrets(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	assert.NoError(t, err)
+	defer func() { os.Remove(tmpfile.Name()) }()
+
+	internal.AddSecret("s3cr3t-password")
+	SetupLogging(LogConfig{Logfile: tmpfile.Name()})
+	log.Printf("E! login failed with s3cr3t-password")
+
+	f, err := ioutil.ReadFile(tmpfile.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, f[19:], []byte("Z E! login failed with ****\n"))
+}
+
+func TestJSONFormat(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	assert.NoError(t, err)
+	defer func() { os.Remove(tmpfile.Name()) }()
+
+	SetupLogging(LogConfig{
+		Logfile:         tmpfile.Name(),
+		Format:          "json",
+		TimestampFormat: "2006",
+	})
+	log.Printf("W! <TEST>")
+	New("inputs.cpu", "local", "").Errorf("failed: %d", 42)
+
+	f, err := ioutil.ReadFile(tmpfile.Name())
+	assert.NoError(t, err)
+	year := time.Now().UTC().Format("2006")
+	assert.Equal(t,
+		`{"time":"`+year+`","level":"warn","msg":"<TEST>"}`+"\n"+
+			`{"time":"`+year+`","level":"error","plugin":"inputs.cpu","alias":"local","msg":"failed: 42"}`+"\n",
+		string(f))
+}
+
+func TestPluginLogger(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	assert.NoError(t, err)
+	defer func() { os.Remove(tmpfile.Name()) }()
+
+	SetupLogging(LogConfig{Logfile: tmpfile.Name()})
+	New("outputs.file", "", "").Info("TEST")
+	New("outputs.file", "", "").Debug("ignored")
+	New("inputs.cpu", "local", "debug").Debugf("TEST %s", "debug")
+	New("inputs.mem", "", "error").Warn("ignored")
+
+	f, err := ioutil.ReadFile(tmpfile.Name())
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(f)), "\n")
+	assert.Len(t, lines, 2)
+	assert.Equal(t, "Z I! [outputs.file] TEST", lines[0][19:])
+	assert.Equal(t, "Z D! [inputs.cpu::local] TEST debug", lines[1][19:])
+}
+
+func TestReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	logfile := filepath.Join(dir, "telegraf.log")
+
+	SetupLogging(LogConfig{Logfile: logfile})
+	log.Printf("I! before")
+	assert.NoError(t, os.Rename(logfile, logfile+".1"))
+	assert.NoError(t, Reopen())
+	log.Printf("I! after")
+	SetupLogging(LogConfig{})
+
+	f, err := ioutil.ReadFile(logfile + ".1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Z I! before\n", string(f[19:]))
+	f, err = ioutil.ReadFile(logfile)
+	assert.NoError(t, err)
+	assert.Equal(t, "Z I! after\n", string(f[19:]))
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("Debug")
+	assert.NoError(t, err)
+	assert.Equal(t, LevelDebug, level)
+
+	_, err = ParseLevel("trace")
+	assert.Error(t, err)
+}
+
 func BenchmarkTelegrafLogWrite(b *testing.B) {
 	var msg = []byte("test")
 	var buf bytes.Buffer
-	w := newTelegrafWriter(&buf)
+	w := newTelegrafWriter(&output{
+		writer:          &buf,
+		level:           LevelInfo,
+		timestampFormat: time.RFC3339,
+	})
 	for i := 0; i < b.N; i++ {
 		buf.Reset()
 		w.Write(msg)
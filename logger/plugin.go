@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+)
+
+// pluginLogger is the telegraf.Logger of a plugin instance.
+type pluginLogger struct {
+	plugin string
+	alias  string
+	// level overrides the level of the output when set.
+	level *Level
+}
+
+// New returns the logger of a plugin instance, where plugin is the full name
+// of the plugin, eg. "inputs.cpu". The level, when not empty, overrides the
+// log level of the agent for this plugin; an invalid level is ignored.
+func New(plugin, alias, level string) telegraf.Logger {
+	l := &pluginLogger{plugin: plugin, alias: alias}
+	if level != "" {
+		if lvl, err := ParseLevel(level); err == nil {
+			l.level = &lvl
+		}
+	}
+	return l
+}
+
+func (l *pluginLogger) log(level Level, msg string) {
+	if l.level != nil {
+		if level > *l.level {
+			return
+		}
+	} else if !out.enabled(level) {
+		return
+	}
+	out.write(level, l.plugin, l.alias, msg)
+}
+
+func (l *pluginLogger) Errorf(format string, args ...interface{}) {
+	l.log(LevelError, fmt.Sprintf(format, args...))
+}
+
+func (l *pluginLogger) Error(args ...interface{}) {
+	l.log(LevelError, fmt.Sprint(args...))
+}
+
+func (l *pluginLogger) Warnf(format string, args ...interface{}) {
+	l.log(LevelWarn, fmt.Sprintf(format, args...))
+}
+
+func (l *pluginLogger) Warn(args ...interface{}) {
+	l.log(LevelWarn, fmt.Sprint(args...))
+}
+
+func (l *pluginLogger) Infof(format string, args ...interface{}) {
+	l.log(LevelInfo, fmt.Sprintf(format, args...))
+}
+
+func (l *pluginLogger) Info(args ...interface{}) {
+	l.log(LevelInfo, fmt.Sprint(args...))
+}
+
+func (l *pluginLogger) Debugf(format string, args ...interface{}) {
+	l.log(LevelDebug, fmt.Sprintf(format, args...))
+}
+
+func (l *pluginLogger) Debug(args ...interface{}) {
+	l.log(LevelDebug, fmt.Sprint(args...))
+}
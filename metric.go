@@ -59,4 +59,15 @@ type Metric interface {
 	// aggregator things:
 	SetAggregate(bool)
 	IsAggregate() bool
+
+	// Delivery tracking, see TrackingAccumulator. These do nothing for
+	// metrics that are not tracked.
+	//
+	// Accept marks the metric as written by an output.
+	Accept()
+	// Reject marks the metric as lost, eg. dropped from a full buffer.
+	Reject()
+	// Drop marks the metric as processed without being written, eg. filtered
+	// out or aggregated.
+	Drop()
 }
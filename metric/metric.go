@@ -151,6 +151,12 @@ func (m *metric) IsAggregate() bool {
 	return m.aggregate
 }
 
+func (m *metric) Accept() {}
+
+func (m *metric) Reject() {}
+
+func (m *metric) Drop() {}
+
 func (m *metric) Type() telegraf.ValueType {
 	return m.mType
 }
@@ -409,17 +415,13 @@ func (m *metric) AddTag(key, value string) {
 }
 
 func (m *metric) HasTag(key string) bool {
-	i := bytes.Index(m.tags, []byte(escape(key, "tagkey")+"="))
-	if i == -1 {
-		return false
-	}
-	return true
+	return m.tagIndex(key) != -1
 }
 
 func (m *metric) RemoveTag(key string) {
 	m.hashID = 0
 
-	i := bytes.Index(m.tags, []byte(escape(key, "tagkey")+"="))
+	i := m.tagIndex(key)
 	if i == -1 {
 		return
 	}
@@ -433,32 +435,36 @@ func (m *metric) RemoveTag(key string) {
 	return
 }
 
+// AddField adds a field, replacing the value of an existing field of the same
+// name.
 func (m *metric) AddField(key string, value interface{}) {
+	exists := m.HasField(key)
 	m.fields = append(m.fields, ',')
 	m.fields = appendField(m.fields, key, value)
+	if exists {
+		// the existing field is found first, and is only removed once the
+		// new one is added since a metric cannot be left without fields.
+		m.RemoveField(key)
+	}
 }
 
 func (m *metric) HasField(key string) bool {
-	i := bytes.Index(m.fields, []byte(escape(key, "tagkey")+"="))
-	if i == -1 {
-		return false
-	}
-	return true
+	i, _ := m.fieldIndex(key)
+	return i != -1
 }
 
 func (m *metric) RemoveField(key string) error {
-	i := bytes.Index(m.fields, []byte(escape(key, "tagkey")+"="))
+	i, j := m.fieldIndex(key)
 	if i == -1 {
 		return nil
 	}
 
 	var tmp []byte
 	if i != 0 {
-		tmp = m.fields[0 : i-1]
-	}
-	j := indexUnescapedByte(m.fields[i:], ',')
-	if j != -1 {
-		tmp = append(tmp, m.fields[i+j:]...)
+		tmp = append(m.fields[0:i-1], m.fields[j:]...)
+	} else if j != len(m.fields) {
+		// the first field has no leading comma to remove
+		tmp = m.fields[j+1:]
 	}
 
 	if len(tmp) == 0 {
@@ -469,6 +475,61 @@ func (m *metric) RemoveField(key string) error {
 	return nil
 }
 
+// tagIndex returns the index of the key of the tag, or -1. The key must
+// follow an unescaped comma, it may otherwise be the end of another key or be
+// part of a value.
+func (m *metric) tagIndex(key string) int {
+	k := []byte("," + escape(key, "tagkey") + "=")
+	i := 0
+	for {
+		j := bytes.Index(m.tags[i:], k)
+		if j == -1 {
+			return -1
+		}
+		i += j
+		if countBackslashes(m.tags, i-1)%2 == 0 {
+			return i + 1
+		}
+		i++
+	}
+}
+
+// fieldIndex returns the index of the key of the field and the index of the
+// end of its value, or -1. The fields are walked one by one since string
+// values may contain unescaped commas and equal signs.
+func (m *metric) fieldIndex(key string) (int, int) {
+	k := []byte(escape(key, "tagkey") + "=")
+	i := 0
+	for i < len(m.fields) {
+		eq := indexUnescapedByte(m.fields[i:], '=')
+		if eq == -1 {
+			return -1, -1
+		}
+
+		// end index of the field value
+		end := i + eq + 1
+		if end < len(m.fields) && m.fields[end] == '"' {
+			j := indexUnescapedByte(m.fields[end+1:], '"')
+			if j == -1 {
+				return -1, -1
+			}
+			end += j + 2
+		} else {
+			j := indexUnescapedByte(m.fields[end:], ',')
+			if j == -1 {
+				j = len(m.fields) - end
+			}
+			end += j
+		}
+
+		if bytes.HasPrefix(m.fields[i:], k) {
+			return i, end
+		}
+		i = end + 1
+	}
+	return -1, -1
+}
+
 func (m *metric) Copy() telegraf.Metric {
 	return copyWith(m.name, m.tags, m.fields, m.t)
 }
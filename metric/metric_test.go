@@ -242,6 +242,43 @@ func TestNewMetric_FieldModifiers(t *testing.T) {
 	m.AddField("value2", int64(101))
 	assert.NoError(t, m.RemoveField("value"))
 	assert.False(t, m.HasField("value"))
+	assert.Equal(t, map[string]interface{}{"value2": int64(101)}, m.Fields())
+	// adding an existing field replaces its value:
+	m.AddField("value2", "replaced")
+	assert.Equal(t, map[string]interface{}{"value2": "replaced"}, m.Fields())
+}
+
+func TestNewMetric_SimilarKeys(t *testing.T) {
+	m, err := New("cpu",
+		map[string]string{"xhost": "a", "host": "b", "other": "x,host=c"},
+		map[string]interface{}{
+			"int_float": int64(2),
+			"text":      "a,float=1",
+			"float":     1.5,
+		},
+		time.Now())
+	require.NoError(t, err)
+
+	m.RemoveTag("host")
+	assert.Equal(t, map[string]string{"xhost": "a", "other": "x,host=c"}, m.Tags())
+	assert.False(t, m.HasTag("host"))
+	m.AddTag("host", "d")
+	assert.Equal(t, "a", m.Tags()["xhost"])
+
+	assert.NoError(t, m.RemoveField("float"))
+	assert.Equal(t, map[string]interface{}{
+		"int_float": int64(2),
+		"text":      "a,float=1",
+	}, m.Fields())
+	assert.False(t, m.HasField("float"))
+
+	m.AddField("float", 2.5)
+	m.AddField("float", 3.5)
+	assert.Equal(t, map[string]interface{}{
+		"int_float": int64(2),
+		"text":      "a,float=1",
+		"float":     3.5,
+	}, m.Fields())
 }
 
 func TestNewMetric_Fields(t *testing.T) {
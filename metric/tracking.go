@@ -0,0 +1,113 @@
+package metric
+
+import (
+	"sync/atomic"
+
+	"github.com/influxdata/telegraf"
+)
+
+// NotifyFunc is called with the outcome of a tracked metric group, once every
+// metric of the group and all their copies have been accepted, rejected or
+// dropped.
+type NotifyFunc func(info telegraf.DeliveryInfo)
+
+var lastTrackingID uint64
+
+func newTrackingID() telegraf.TrackingID {
+	return telegraf.TrackingID(atomic.AddUint64(&lastTrackingID, 1))
+}
+
+// WithGroupTracking returns the metrics tracked as a group, and the id of the
+// group. notify is called immediately when the group is empty.
+func WithGroupTracking(
+	metrics []telegraf.Metric,
+	notify NotifyFunc,
+) ([]telegraf.Metric, telegraf.TrackingID) {
+	d := &trackingData{
+		id:     newTrackingID(),
+		refs:   int32(len(metrics)),
+		notify: notify,
+	}
+	if len(metrics) == 0 {
+		notify(&deliveryInfo{id: d.id, delivered: true})
+		return nil, d.id
+	}
+
+	tracked := make([]telegraf.Metric, len(metrics))
+	for i, m := range metrics {
+		tracked[i] = &trackingMetric{Metric: m, d: d}
+	}
+	return tracked, d.id
+}
+
+// WithTrackingOf returns m tracked in the group of original, for metrics
+// that a processor creates in place of original: the group is only notified
+// once m is accepted, rejected or dropped too. m is returned as is when
+// original is not tracked.
+func WithTrackingOf(m, original telegraf.Metric) telegraf.Metric {
+	t, ok := original.(*trackingMetric)
+	if !ok {
+		return m
+	}
+	t.d.incr()
+	return &trackingMetric{Metric: m, d: t.d}
+}
+
+// trackingData is shared by the metrics of a group and their copies.
+type trackingData struct {
+	id       telegraf.TrackingID
+	refs     int32
+	rejected int32
+	notify   NotifyFunc
+}
+
+func (d *trackingData) incr() {
+	atomic.AddInt32(&d.refs, 1)
+}
+
+func (d *trackingData) decr() {
+	if atomic.AddInt32(&d.refs, -1) == 0 {
+		d.notify(&deliveryInfo{
+			id:        d.id,
+			delivered: atomic.LoadInt32(&d.rejected) == 0,
+		})
+	}
+}
+
+// trackingMetric is a metric of a tracked group. Each copy counts as one more
+// metric of the group, to be accepted, rejected or dropped on its own.
+type trackingMetric struct {
+	telegraf.Metric
+	d *trackingData
+}
+
+func (m *trackingMetric) Copy() telegraf.Metric {
+	m.d.incr()
+	return &trackingMetric{Metric: m.Metric.Copy(), d: m.d}
+}
+
+func (m *trackingMetric) Accept() {
+	m.d.decr()
+}
+
+func (m *trackingMetric) Reject() {
+	atomic.StoreInt32(&m.d.rejected, 1)
+	m.d.decr()
+}
+
+func (m *trackingMetric) Drop() {
+	m.d.decr()
+}
+
+type deliveryInfo struct {
+	id        telegraf.TrackingID
+	delivered bool
+}
+
+func (i *deliveryInfo) ID() telegraf.TrackingID {
+	return i.id
+}
+
+func (i *deliveryInfo) Delivered() bool {
+	return i.delivered
+}
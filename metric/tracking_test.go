@@ -0,0 +1,98 @@
+package metric
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func trackedGroup(
+	t *testing.T,
+	n int,
+) ([]telegraf.Metric, telegraf.TrackingID, *[]telegraf.DeliveryInfo) {
+	var metrics []telegraf.Metric
+	for i := 0; i < n; i++ {
+		m, err := New("cpu", map[string]string{"host": "localhost"},
+			map[string]interface{}{"value": 42}, time.Unix(0, 0))
+		require.NoError(t, err)
+		metrics = append(metrics, m)
+	}
+
+	var infos []telegraf.DeliveryInfo
+	tracked, id := WithGroupTracking(metrics,
+		func(info telegraf.DeliveryInfo) {
+			infos = append(infos, info)
+		})
+	return tracked, id, &infos
+}
+
+func TestTrackingDelivered(t *testing.T) {
+	metrics, id, infos := trackedGroup(t, 2)
+	require.Len(t, metrics, 2)
+
+	metrics[0].Accept()
+	assert.Len(t, *infos, 0)
+	metrics[1].Drop()
+	require.Len(t, *infos, 1)
+	assert.Equal(t, id, (*infos)[0].ID())
+	assert.True(t, (*infos)[0].Delivered())
+}
+
+func TestTrackingRejected(t *testing.T) {
+	metrics, _, infos := trackedGroup(t, 2)
+
+	metrics[0].Reject()
+	metrics[1].Accept()
+	require.Len(t, *infos, 1)
+	assert.False(t, (*infos)[0].Delivered())
+}
+
+func TestTrackingCopy(t *testing.T) {
+	metrics, _, infos := trackedGroup(t, 1)
+
+	c := metrics[0].Copy()
+	c.AddTag("output", "file")
+	assert.False(t, metrics[0].HasTag("output"))
+
+	metrics[0].Accept()
+	assert.Len(t, *infos, 0)
+	c.Accept()
+	require.Len(t, *infos, 1)
+	assert.True(t, (*infos)[0].Delivered())
+}
+
+func TestTrackingOf(t *testing.T) {
+	metrics, _, infos := trackedGroup(t, 1)
+
+	status, err := New("cpu_status", map[string]string{},
+		map[string]interface{}{"state": "ok"}, time.Unix(0, 0))
+	require.NoError(t, err)
+	m := WithTrackingOf(status, metrics[0])
+
+	metrics[0].Drop()
+	assert.Len(t, *infos, 0)
+	m.Reject()
+	require.Len(t, *infos, 1)
+	assert.False(t, (*infos)[0].Delivered())
+
+	// the metric is returned as is with an untracked metric
+	assert.True(t, status == WithTrackingOf(status, status.Copy()))
+}
+
+func TestTrackingEmptyGroup(t *testing.T) {
+	metrics, id, infos := trackedGroup(t, 0)
+	assert.Len(t, metrics, 0)
+	require.Len(t, *infos, 1)
+	assert.Equal(t, id, (*infos)[0].ID())
+	assert.True(t, (*infos)[0].Delivered())
+}
+
+func TestTrackingIDsAreUnique(t *testing.T) {
+	_, id1, _ := trackedGroup(t, 1)
+	_, id2, _ := trackedGroup(t, 1)
+	assert.NotEqual(t, id1, id2)
+}
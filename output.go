@@ -1,5 +1,7 @@
 package telegraf
 
+import "context"
+
 type Output interface {
 	// Connect to the Output
 	Connect() error
@@ -13,6 +15,16 @@ type Output interface {
 	Write(metrics []Metric) error
 }
 
+// ContextOutput is an Output that stops writing when its context is done, when
+// telegraf shuts down. The agent calls WriteContext instead of Write for these
+// outputs.
+type ContextOutput interface {
+	Output
+
+	// WriteContext is Write, cancelled with ctx.
+	WriteContext(ctx context.Context, metrics []Metric) error
+}
+
 type ServiceOutput interface {
 	// Connect to the Output
 	Connect() error
@@ -29,3 +41,13 @@ type ServiceOutput interface {
 	// Stop the "service" that will provide an Output
 	Stop()
 }
+
+// PermanentError can be implemented by errors returned from Output.Write to
+// tell telegraf whether retrying the write can succeed. When Permanent returns
+// true, for example when the server rejected the metrics as invalid, the
+// batch is dropped instead of being retried. Errors that do not implement
+// PermanentError are considered transient.
+type PermanentError interface {
+	error
+	Permanent() bool
+}
@@ -28,6 +28,9 @@ type AMQPConsumer struct {
 	// Controls how many messages the server will try to keep on the network
 	// for consumers before receiving delivery acks.
 	PrefetchCount int
+	// Maximum number of messages whose metrics are not yet written by the
+	// outputs. Messages are acknowledged once their metrics are written.
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
 
 	// AMQP Auth method
 	AuthMethod string
@@ -55,8 +58,9 @@ func (a *externalAuth) Response() string {
 }
 
 const (
-	DefaultAuthMethod    = "PLAIN"
-	DefaultPrefetchCount = 50
+	DefaultAuthMethod             = "PLAIN"
+	DefaultPrefetchCount          = 50
+	DefaultMaxUndeliveredMessages = 1000
 )
 
 func (a *AMQPConsumer) SampleConfig() string {
@@ -70,6 +74,11 @@ func (a *AMQPConsumer) SampleConfig() string {
   ## Binding Key
   binding_key = "#"
 
+  ## Maximum number of messages whose metrics are not yet written by the
+  ## outputs. Messages are only acknowledged once their metrics are written,
+  ## the consumer pauses when this many messages are undelivered.
+  # max_undelivered_messages = 1000
+
   ## Maximum number of messages server should give to the worker.
   prefetch_count = 50
 
@@ -130,6 +139,10 @@ func (a *AMQPConsumer) createConfig() (*amqp.Config, error) {
 
 // Start satisfies the telegraf.ServiceInput interface
 func (a *AMQPConsumer) Start(acc telegraf.Accumulator) error {
+	if a.MaxUndeliveredMessages < 1 {
+		a.MaxUndeliveredMessages = DefaultMaxUndeliveredMessages
+	}
+
 	amqpConf, err := a.createConfig()
 	if err != nil {
 		return err
@@ -242,22 +255,53 @@ func (a *AMQPConsumer) connect(amqpConf *amqp.Config) (<-chan amqp.Delivery, err
 	return msgs, err
 }
 
-// Read messages from queue and add them to the Accumulator
-func (a *AMQPConsumer) process(msgs <-chan amqp.Delivery, acc telegraf.Accumulator) {
+// Read messages from queue and add them to the Accumulator. A message is
+// acknowledged once its metrics are written by the outputs, and rejected if
+// they are lost.
+func (a *AMQPConsumer) process(msgs <-chan amqp.Delivery, ac telegraf.Accumulator) {
 	defer a.wg.Done()
-	for d := range msgs {
-		metrics, err := a.parser.Parse(d.Body)
-		if err != nil {
-			log.Printf("E! %v: error parsing metric - %v", err, string(d.Body))
-		} else {
-			for _, m := range metrics {
-				acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
-			}
+	// the deliveries of a closed channel cannot be acknowledged anymore, each
+	// channel tracks its own deliveries.
+	acc := ac.WithTracking(a.MaxUndeliveredMessages)
+	undelivered := make(map[telegraf.TrackingID]amqp.Delivery)
+	for {
+		// stop reading messages while max_undelivered_messages are
+		// undelivered.
+		in := msgs
+		if len(undelivered) >= a.MaxUndeliveredMessages {
+			in = nil
 		}
 
-		d.Ack(false)
+		select {
+		case track := <-acc.Delivered():
+			d, ok := undelivered[track.ID()]
+			if !ok {
+				continue
+			}
+			delete(undelivered, track.ID())
+			var err error
+			if track.Delivered() {
+				err = d.Ack(false)
+			} else {
+				err = d.Reject(false)
+			}
+			if err != nil {
+				log.Printf("E! Unable to acknowledge AMQP message: %s", err)
+			}
+		case d, ok := <-in:
+			if !ok {
+				log.Printf("I! AMQP consumer queue closed")
+				return
+			}
+			metrics, err := a.parser.Parse(d.Body)
+			if err != nil {
+				log.Printf("E! %v: error parsing metric - %v", err, string(d.Body))
+				d.Ack(false)
+				continue
+			}
+			undelivered[acc.AddTrackingMetricGroup(metrics)] = d
+		}
 	}
-	log.Printf("I! AMQP consumer queue closed")
 }
 
 func (a *AMQPConsumer) Stop() {
@@ -273,8 +317,9 @@ func (a *AMQPConsumer) Stop() {
 func init() {
 	inputs.Add("amqp_consumer", func() telegraf.Input {
 		return &AMQPConsumer{
-			AuthMethod:    DefaultAuthMethod,
-			PrefetchCount: DefaultPrefetchCount,
+			AuthMethod:             DefaultAuthMethod,
+			PrefetchCount:          DefaultPrefetchCount,
+			MaxUndeliveredMessages: DefaultMaxUndeliveredMessages,
 		}
 	})
 }
@@ -1,6 +1,7 @@
 package http_response
 
 import (
+	"context"
 	"errors"
 	"io"
 	"io/ioutil"
@@ -108,7 +109,7 @@ func (h *HTTPResponse) createHttpClient() (*http.Client, error) {
 }
 
 // HTTPGather gathers all fields and returns any errors it encounters
-func (h *HTTPResponse) httpGather() (map[string]interface{}, error) {
+func (h *HTTPResponse) httpGather(ctx context.Context) (map[string]interface{}, error) {
 	// Prepare fields
 	fields := make(map[string]interface{})
 
@@ -130,9 +131,13 @@ func (h *HTTPResponse) httpGather() (map[string]interface{}, error) {
 
 	// Start Timer
 	start := time.Now()
-	resp, err := h.client.Do(request)
+	resp, err := h.client.Do(request.WithContext(ctx))
 
 	if err != nil {
+		// a cancelled request is not a failure of the server.
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
 			fields["result_type"] = "timeout"
 			return fields, nil
@@ -193,6 +198,14 @@ func (h *HTTPResponse) httpGather() (map[string]interface{}, error) {
 
 // Gather gets all metric fields and tags and returns any errors it encounters
 func (h *HTTPResponse) Gather(acc telegraf.Accumulator) error {
+	return h.GatherContext(context.Background(), acc)
+}
+
+// GatherContext is Gather, cancelling the request when ctx is done.
+func (h *HTTPResponse) GatherContext(
+	ctx context.Context,
+	acc telegraf.Accumulator,
+) error {
 	// Set default values
 	if h.ResponseTimeout.Duration < time.Second {
 		h.ResponseTimeout.Duration = time.Second * 5
@@ -224,7 +237,7 @@ func (h *HTTPResponse) Gather(acc telegraf.Accumulator) error {
 	}
 
 	// Gather data
-	fields, err = h.httpGather()
+	fields, err = h.httpGather(ctx)
 	if err != nil {
 		return err
 	}
@@ -1,6 +1,7 @@
 package http_response
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -388,3 +389,21 @@ func TestTimeout(t *testing.T) {
 	_, ok = acc.FloatField("http_response", "response_time")
 	require.False(t, ok)
 }
+
+func TestGatherContextCancelled(t *testing.T) {
+	mux := setUpTestMux()
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	h := &HTTPResponse{
+		Address:         ts.URL + "/good",
+		Method:          "GET",
+		ResponseTimeout: internal.Duration{Duration: time.Second * 20},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var acc testutil.Accumulator
+	assert.Error(t, h.GatherContext(ctx, &acc))
+	assert.False(t, acc.HasMeasurement("http_response"))
+}
@@ -1,6 +1,7 @@
 package httpjson
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -125,6 +126,15 @@ func (h *HttpJson) Description() string {
 
 // Gathers data for all servers.
 func (h *HttpJson) Gather(acc telegraf.Accumulator) error {
+	return h.GatherContext(context.Background(), acc)
+}
+
+// GatherContext gathers data for all servers, cancelling the requests when
+// ctx is done.
+func (h *HttpJson) GatherContext(
+	ctx context.Context,
+	acc telegraf.Accumulator,
+) error {
 	var wg sync.WaitGroup
 
 	if h.client.HTTPClient() == nil {
@@ -148,7 +158,7 @@ func (h *HttpJson) Gather(acc telegraf.Accumulator) error {
 		wg.Add(1)
 		go func(server string) {
 			defer wg.Done()
-			acc.AddError(h.gatherServer(acc, server))
+			acc.AddError(h.gatherServer(ctx, acc, server))
 		}(server)
 	}
 
@@ -159,6 +169,7 @@ func (h *HttpJson) Gather(acc telegraf.Accumulator) error {
 
 // Gathers data from a particular server
 // Parameters:
+//     ctx      : The context of the request
 //     acc      : The telegraf Accumulator to use
 //     serverURL: endpoint to send request to
 //     service  : the service being queried
@@ -166,10 +177,11 @@ func (h *HttpJson) Gather(acc telegraf.Accumulator) error {
 // Returns:
 //     error: Any error that may have occurred
 func (h *HttpJson) gatherServer(
+	ctx context.Context,
 	acc telegraf.Accumulator,
 	serverURL string,
 ) error {
-	resp, responseTime, err := h.sendRequest(serverURL)
+	resp, responseTime, err := h.sendRequest(ctx, serverURL)
 
 	if err != nil {
 		return err
@@ -209,12 +221,16 @@ func (h *HttpJson) gatherServer(
 // Sends an HTTP request to the server using the HttpJson object's HTTPClient.
 // This request can be either a GET or a POST.
 // Parameters:
+//     ctx      : The context of the request
 //     serverURL: endpoint to send request to
 //
 // Returns:
 //     string: body of the response
 //     error : Any error that may have occurred
-func (h *HttpJson) sendRequest(serverURL string) (string, float64, error) {
+func (h *HttpJson) sendRequest(
+	ctx context.Context,
+	serverURL string,
+) (string, float64, error) {
 	// Prepare URL
 	requestURL, err := url.Parse(serverURL)
 	if err != nil {
@@ -254,7 +270,7 @@ func (h *HttpJson) sendRequest(serverURL string) (string, float64, error) {
 	}
 
 	start := time.Now()
-	resp, err := h.client.MakeRequest(req)
+	resp, err := h.client.MakeRequest(req.WithContext(ctx))
 	if err != nil {
 		return "", -1, err
 	}
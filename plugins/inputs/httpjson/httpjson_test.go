@@ -1,6 +1,7 @@
 package httpjson
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -560,3 +561,28 @@ func TestHttpJsonArray200Tags(t *testing.T) {
 		}
 	}
 }
+
+// Test that the request is cancelled with the context of GatherContext
+func TestHttpJsonGatherContextCancel(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		fmt.Fprintln(w, validJSON)
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	a := HttpJson{
+		Servers: []string{ts.URL},
+		Method:  "GET",
+		client:  &RealHTTPClient{client: &http.Client{}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var acc testutil.Accumulator
+	go cancel()
+	require.NoError(t, a.GatherContext(ctx, &acc))
+	require.Len(t, acc.Errors, 1)
+	assert.Contains(t, acc.Errors[0].Error(), "canceled")
+	assert.Equal(t, uint64(0), acc.NMetrics())
+}
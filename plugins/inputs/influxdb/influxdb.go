@@ -1,6 +1,7 @@
 package influxdb
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -58,6 +59,14 @@ func (*InfluxDB) SampleConfig() string {
 }
 
 func (i *InfluxDB) Gather(acc telegraf.Accumulator) error {
+	return i.GatherContext(context.Background(), acc)
+}
+
+// GatherContext is Gather, cancelling the requests when ctx is done.
+func (i *InfluxDB) GatherContext(
+	ctx context.Context,
+	acc telegraf.Accumulator,
+) error {
 	if len(i.URLs) == 0 {
 		i.URLs = []string{"http://localhost:8086/debug/vars"}
 	}
@@ -82,7 +91,7 @@ func (i *InfluxDB) Gather(acc telegraf.Accumulator) error {
 		wg.Add(1)
 		go func(url string) {
 			defer wg.Done()
-			if err := i.gatherURL(acc, url); err != nil {
+			if err := i.gatherURL(ctx, acc, url); err != nil {
 				acc.AddError(fmt.Errorf("[url=%s]: %s", url, err))
 			}
 		}(u)
@@ -131,19 +140,25 @@ type memstats struct {
 
 // Gathers data from a particular URL
 // Parameters:
+//     ctx    : The context of the request
 //     acc    : The telegraf Accumulator to use
 //     url    : endpoint to send request to
 //
 // Returns:
 //     error: Any error that may have occurred
 func (i *InfluxDB) gatherURL(
+	ctx context.Context,
 	acc telegraf.Accumulator,
 	url string,
 ) error {
 	shardCounter := 0
 	now := time.Now()
 
-	resp, err := i.client.Get(url)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := i.client.Do(req.WithContext(ctx))
 	if err != nil {
 		return err
 	}
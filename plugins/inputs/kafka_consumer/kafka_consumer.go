@@ -43,7 +43,10 @@ type Kafka struct {
 	PointBuffer int
 
 	Offset string
-	parser parsers.Parser
+	// MaxUndeliveredMessages is the number of messages whose metrics may
+	// be waiting to be written by the outputs.
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+	parser                 parsers.Parser
 
 	sync.Mutex
 
@@ -54,13 +57,15 @@ type Kafka struct {
 	done chan struct{}
 
 	// keep the accumulator internally:
-	acc telegraf.Accumulator
+	acc telegraf.TrackingAccumulator
 
 	// doNotCommitMsgs tells the parser not to call CommitUpTo on the consumer
 	// this is mostly for test purposes, but there may be a use-case for it later.
 	doNotCommitMsgs bool
 }
 
+const defaultMaxUndeliveredMessages = 1000
+
 var sampleConfig = `
   ## kafka servers
   brokers = ["localhost:9092"]
@@ -92,6 +97,11 @@ var sampleConfig = `
   ## Maximum length of a message to consume, in bytes (default 0/unlimited);
   ## larger messages are dropped
   max_message_len = 65536
+
+  ## Maximum number of messages whose metrics are not yet written by the
+  ## outputs. The offset of a message is only committed once its metrics are
+  ## written, the consumer pauses when this many messages are undelivered.
+  # max_undelivered_messages = 1000
 `
 
 func (k *Kafka) SampleConfig() string {
@@ -111,7 +121,10 @@ func (k *Kafka) Start(acc telegraf.Accumulator) error {
 	defer k.Unlock()
 	var clusterErr error
 
-	k.acc = acc
+	if k.MaxUndeliveredMessages < 1 {
+		k.MaxUndeliveredMessages = defaultMaxUndeliveredMessages
+	}
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 
 	config := cluster.NewConfig()
 	config.Consumer.Return.Errors = true
@@ -174,42 +187,133 @@ func (k *Kafka) Start(acc telegraf.Accumulator) error {
 }
 
 // receiver() reads all incoming messages from the consumer, and parses them into
-// influxdb metric points.
+// influxdb metric points. The offset of a message is committed once its
+// metrics have been delivered to the outputs.
 func (k *Kafka) receiver() {
+	undelivered := make(map[telegraf.TrackingID]*message)
+	offsets := make(offsets)
 	for {
+		// stop reading messages while max_undelivered_messages are
+		// undelivered.
+		in := k.in
+		if len(undelivered) >= k.MaxUndeliveredMessages {
+			in = nil
+		}
+
 		select {
 		case <-k.done:
 			return
-		case err := <-k.errs:
-			if err != nil {
-				k.acc.AddError(fmt.Errorf("Consumer Error: %s\n", err))
+		case track := <-k.acc.Delivered():
+			msg, ok := undelivered[track.ID()]
+			if !ok {
+				continue
 			}
-		case msg := <-k.in:
-			if k.MaxMessageLen != 0 && len(msg.Value) > k.MaxMessageLen {
-				k.acc.AddError(fmt.Errorf("Message longer than max_message_len (%d > %d)",
-					len(msg.Value), k.MaxMessageLen))
-			} else {
-				metrics, err := k.parser.Parse(msg.Value)
-				if err != nil {
-					k.acc.AddError(fmt.Errorf("Message Parse Error\nmessage: %s\nerror: %s",
-						string(msg.Value), err.Error()))
-				}
-				for _, metric := range metrics {
-					k.acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
-				}
+			delete(undelivered, track.ID())
+			if !track.Delivered() {
+				// the message is consumed again once the consumer is
+				// restarted.
+				offsets.reject(msg)
+				k.acc.AddError(fmt.Errorf("Message at offset %d of partition %d of topic %s not delivered, no later offset of the partition is committed",
+					msg.Offset, msg.Partition, msg.Topic))
+				continue
 			}
-
-			if !k.doNotCommitMsgs {
+			if commit := offsets.done(msg); commit != nil && !k.doNotCommitMsgs {
 				// TODO(cam) this locking can be removed if this PR gets merged:
 				// https://github.com/wvanbergen/kafka/pull/84
 				k.Lock()
-				k.Cluster.MarkOffset(msg, "")
+				k.Cluster.MarkOffset(commit, "")
 				k.Unlock()
 			}
+		case err := <-k.errs:
+			if err != nil {
+				k.acc.AddError(fmt.Errorf("Consumer Error: %s\n", err))
+			}
+		case msg := <-in:
+			// messages without metrics are tracked too, so that the offsets
+			// are committed in order.
+			id := k.acc.AddTrackingMetricGroup(k.parse(msg))
+			undelivered[id] = offsets.add(msg)
 		}
 	}
 }
 
+// parse returns the metrics of the message, nil if it is invalid.
+func (k *Kafka) parse(msg *sarama.ConsumerMessage) []telegraf.Metric {
+	if k.MaxMessageLen != 0 && len(msg.Value) > k.MaxMessageLen {
+		k.acc.AddError(fmt.Errorf("Message longer than max_message_len (%d > %d)",
+			len(msg.Value), k.MaxMessageLen))
+		return nil
+	}
+	metrics, err := k.parser.Parse(msg.Value)
+	if err != nil {
+		k.acc.AddError(fmt.Errorf("Message Parse Error\nmessage: %s\nerror: %s",
+			string(msg.Value), err.Error()))
+	}
+	return metrics
+}
+
+// message is a consumed message, done once its metrics are delivered and
+// rejected if they are not.
+type message struct {
+	*sarama.ConsumerMessage
+	done     bool
+	rejected bool
+}
+
+type partition struct {
+	topic string
+	id    int32
+}
+
+// offsets keeps the undelivered messages of each partition in the order they
+// were consumed. Committing the offset of a message commits all the messages
+// before it, so it is only committed once they are all done.
+type offsets map[partition][]*message
+
+// add adds a consumed message.
+func (o offsets) add(msg *sarama.ConsumerMessage) *message {
+	m := &message{ConsumerMessage: msg}
+	p := partition{topic: msg.Topic, id: msg.Partition}
+	o[p] = append(o[p], m)
+	return m
+}
+
+// done marks the message as done, and returns the last message of its
+// partition whose offset can be committed, nil if there is none.
+func (o offsets) done(m *message) *sarama.ConsumerMessage {
+	m.done = true
+	return o.pop(m)
+}
+
+// reject marks the message as rejected. No offset of its partition is
+// committed past it anymore.
+func (o offsets) reject(m *message) {
+	m.rejected = true
+	o.pop(m)
+}
+
+// pop removes the done messages at the front of the partition of m, and
+// returns the last of them.
+func (o offsets) pop(m *message) *sarama.ConsumerMessage {
+	p := partition{topic: m.Topic, id: m.Partition}
+	queue := o[p]
+	var commit *sarama.ConsumerMessage
+	for len(queue) > 0 && queue[0].done {
+		commit = queue[0].ConsumerMessage
+		queue = queue[1:]
+	}
+	if len(queue) == 0 {
+		delete(o, p)
+		return commit
+	}
+	if queue[0].rejected {
+		// the later messages are never committed.
+		queue = queue[:1]
+	}
+	o[p] = queue
+	return commit
+}
+
 func (k *Kafka) Stop() {
 	k.Lock()
 	defer k.Unlock()
@@ -225,6 +329,8 @@ func (k *Kafka) Gather(acc telegraf.Accumulator) error {
 
 func init() {
 	inputs.Add("kafka_consumer", func() telegraf.Input {
-		return &Kafka{}
+		return &Kafka{
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+		}
 	})
 }
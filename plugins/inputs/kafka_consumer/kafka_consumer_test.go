@@ -29,6 +29,8 @@ func newTestKafka() (*Kafka, chan *sarama.ConsumerMessage) {
 		doNotCommitMsgs: true,
 		errs:            make(chan error, 1000),
 		done:            make(chan struct{}),
+
+		MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
 	}
 	return &k, in
 }
@@ -37,7 +39,7 @@ func newTestKafka() (*Kafka, chan *sarama.ConsumerMessage) {
 func TestRunParser(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewInfluxParser()
@@ -52,7 +54,7 @@ func TestRunParser(t *testing.T) {
 func TestRunParserInvalidMsg(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewInfluxParser()
@@ -69,7 +71,7 @@ func TestDropOverlongMsg(t *testing.T) {
 	k, in := newTestKafka()
 	k.MaxMessageLen = maxMessageLen
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 	overlongMsg := strings.Repeat("v", maxMessageLen+1)
 
@@ -84,7 +86,7 @@ func TestDropOverlongMsg(t *testing.T) {
 func TestRunParserAndGather(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewInfluxParser()
@@ -103,7 +105,7 @@ func TestRunParserAndGather(t *testing.T) {
 func TestRunParserAndGatherGraphite(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewGraphiteParser("_", []string{}, nil)
@@ -122,7 +124,7 @@ func TestRunParserAndGatherGraphite(t *testing.T) {
 func TestRunParserAndGatherJSON(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewJSONParser("kafka_json_test", []string{}, nil)
@@ -140,6 +142,42 @@ func TestRunParserAndGatherJSON(t *testing.T) {
 		})
 }
 
+// Test that offsets are only committed once all the previous messages of the
+// partition are done
+func TestOffsetsCommitInOrder(t *testing.T) {
+	o := make(offsets)
+	msg1 := o.add(&sarama.ConsumerMessage{Topic: "telegraf", Offset: 1})
+	msg2 := o.add(&sarama.ConsumerMessage{Topic: "telegraf", Offset: 2})
+	msg3 := o.add(&sarama.ConsumerMessage{Topic: "telegraf", Offset: 3})
+	other := o.add(&sarama.ConsumerMessage{Topic: "telegraf", Partition: 1})
+
+	assert.Nil(t, o.done(msg2))
+	assert.Equal(t, msg2.ConsumerMessage, o.done(msg1))
+	assert.Equal(t, other.ConsumerMessage, o.done(other))
+	assert.Equal(t, msg3.ConsumerMessage, o.done(msg3))
+	assert.Len(t, o, 0)
+}
+
+// Test that no offset is committed past a message whose metrics were not
+// delivered
+func TestOffsetsRejected(t *testing.T) {
+	o := make(offsets)
+	msg1 := o.add(&sarama.ConsumerMessage{Topic: "telegraf", Offset: 1})
+	msg2 := o.add(&sarama.ConsumerMessage{Topic: "telegraf", Offset: 2})
+	msg3 := o.add(&sarama.ConsumerMessage{Topic: "telegraf", Offset: 3})
+	other := o.add(&sarama.ConsumerMessage{Topic: "telegraf", Partition: 1})
+
+	assert.Equal(t, msg1.ConsumerMessage, o.done(msg1))
+	o.reject(msg2)
+	assert.Nil(t, o.done(msg3))
+	msg4 := o.add(&sarama.ConsumerMessage{Topic: "telegraf", Offset: 4})
+	assert.Nil(t, o.done(msg4))
+	assert.Equal(t, []*message{msg2}, o[partition{topic: "telegraf"}])
+
+	// the other partitions are still committed
+	assert.Equal(t, other.ConsumerMessage, o.done(other))
+}
+
 func saramaMsg(val string) *sarama.ConsumerMessage {
 	return &sarama.ConsumerMessage{
 		Key:       nil,
@@ -30,6 +30,10 @@ type MQTTConsumer struct {
 	PersistentSession bool
 	ClientID          string `toml:"client_id"`
 
+	// Maximum number of messages whose metrics are not yet written by the
+	// outputs.
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	// Path to CA file
 	SSLCA string `toml:"ssl_ca"`
 	// Path to host cert file
@@ -46,11 +50,13 @@ type MQTTConsumer struct {
 	done chan struct{}
 
 	// keep the accumulator internally:
-	acc telegraf.Accumulator
+	acc telegraf.TrackingAccumulator
 
 	started bool
 }
 
+const defaultMaxUndeliveredMessages = 1000
+
 var sampleConfig = `
   servers = ["localhost:1883"]
   ## MQTT QoS, must be 0, 1, or 2
@@ -70,6 +76,10 @@ var sampleConfig = `
   # If empty, a random client ID will be generated.
   client_id = ""
 
+  ## Maximum number of messages whose metrics are not yet written by the
+  ## outputs. The consumer pauses when this many messages are undelivered.
+  # max_undelivered_messages = 1000
+
   ## username and password to connect MQTT server.
   # username = "telegraf"
   # password = "metricsmetricsmetricsmetrics"
@@ -110,7 +120,10 @@ func (m *MQTTConsumer) Start(acc telegraf.Accumulator) error {
 			" = true, you MUST also set client_id")
 	}
 
-	m.acc = acc
+	if m.MaxUndeliveredMessages < 1 {
+		m.MaxUndeliveredMessages = defaultMaxUndeliveredMessages
+	}
+	m.acc = acc.WithTracking(m.MaxUndeliveredMessages)
 	if m.QoS > 2 || m.QoS < 0 {
 		return fmt.Errorf("MQTT Consumer, invalid QoS value: %d", m.QoS)
 	}
@@ -156,13 +169,25 @@ func (m *MQTTConsumer) onConnectionLost(c mqtt.Client, err error) {
 }
 
 // receiver() reads all incoming messages from the consumer, and parses them into
-// influxdb metric points.
+// influxdb metric points. The MQTT client acknowledges the messages on
+// receipt, tracking their delivery only limits the number of messages held by
+// telegraf.
 func (m *MQTTConsumer) receiver() {
+	undelivered := 0
 	for {
+		// stop reading messages while max_undelivered_messages are
+		// undelivered.
+		in := m.in
+		if undelivered >= m.MaxUndeliveredMessages {
+			in = nil
+		}
+
 		select {
 		case <-m.done:
 			return
-		case msg := <-m.in:
+		case <-m.acc.Delivered():
+			undelivered--
+		case msg := <-in:
 			topic := msg.Topic()
 			metrics, err := m.parser.Parse(msg.Payload())
 			if err != nil {
@@ -171,10 +196,10 @@ func (m *MQTTConsumer) receiver() {
 			}
 
 			for _, metric := range metrics {
-				tags := metric.Tags()
-				tags["topic"] = topic
-				m.acc.AddFields(metric.Name(), metric.Fields(), tags, metric.Time())
+				metric.AddTag("topic", topic)
 			}
+			m.acc.AddTrackingMetricGroup(metrics)
+			undelivered++
 		}
 	}
 }
@@ -243,6 +268,8 @@ func (m *MQTTConsumer) createOpts() (*mqtt.ClientOptions, error) {
 
 func init() {
 	inputs.Add("mqtt_consumer", func() telegraf.Input {
-		return &MQTTConsumer{}
+		return &MQTTConsumer{
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+		}
 	})
 }
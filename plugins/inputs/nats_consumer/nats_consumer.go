@@ -32,6 +32,10 @@ type natsConsumer struct {
 	PendingMessageLimit int
 	PendingBytesLimit   int
 
+	// Maximum number of messages whose metrics are not yet written by the
+	// outputs.
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	// Legacy metric buffer support
 	MetricBuffer int
 
@@ -47,9 +51,11 @@ type natsConsumer struct {
 	// channel for all NATS read errors
 	errs chan error
 	done chan struct{}
-	acc  telegraf.Accumulator
+	acc  telegraf.TrackingAccumulator
 }
 
+const defaultMaxUndeliveredMessages = 1000
+
 var sampleConfig = `
   ## urls of NATS servers
   # servers = ["nats://localhost:4222"]
@@ -65,6 +71,10 @@ var sampleConfig = `
   # pending_message_limit = 65536
   # pending_bytes_limit = 67108864
 
+  ## Maximum number of messages whose metrics are not yet written by the
+  ## outputs. The consumer pauses when this many messages are undelivered.
+  # max_undelivered_messages = 1000
+
   ## Data format to consume.
   ## Each data format has its own unique set of configuration options, read
   ## more about them here:
@@ -97,7 +107,10 @@ func (n *natsConsumer) Start(acc telegraf.Accumulator) error {
 	n.Lock()
 	defer n.Unlock()
 
-	n.acc = acc
+	if n.MaxUndeliveredMessages < 1 {
+		n.MaxUndeliveredMessages = defaultMaxUndeliveredMessages
+	}
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 
 	var connectErr error
 
@@ -154,24 +167,34 @@ func (n *natsConsumer) Start(acc telegraf.Accumulator) error {
 }
 
 // receiver() reads all incoming messages from NATS, and parses them into
-// telegraf metrics.
+// telegraf metrics. NATS messages are not acknowledged, tracking their delivery
+// only limits the number of messages held by telegraf.
 func (n *natsConsumer) receiver() {
 	defer n.wg.Done()
+	undelivered := 0
 	for {
+		// stop reading messages while max_undelivered_messages are
+		// undelivered.
+		in := n.in
+		if undelivered >= n.MaxUndeliveredMessages {
+			in = nil
+		}
+
 		select {
 		case <-n.done:
 			return
+		case <-n.acc.Delivered():
+			undelivered--
 		case err := <-n.errs:
 			n.acc.AddError(fmt.Errorf("E! error reading from %s\n", err.Error()))
-		case msg := <-n.in:
+		case msg := <-in:
 			metrics, err := n.parser.Parse(msg.Data)
 			if err != nil {
 				n.acc.AddError(fmt.Errorf("E! subject: %s, error: %s", msg.Subject, err.Error()))
 			}
 
-			for _, metric := range metrics {
-				n.acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
-			}
+			n.acc.AddTrackingMetricGroup(metrics)
+			undelivered++
 		}
 	}
 }
@@ -210,6 +233,8 @@ func init() {
 			QueueGroup:          "telegraf_consumers",
 			PendingBytesLimit:   nats.DefaultSubPendingBytesLimit,
 			PendingMessageLimit: nats.DefaultSubPendingMsgsLimit,
+
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
 		}
 	})
 }
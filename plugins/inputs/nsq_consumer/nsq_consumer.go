@@ -2,8 +2,11 @@ package nsq_consumer
 
 import (
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/nsqio/go-nsq"
@@ -15,11 +18,35 @@ type NSQConsumer struct {
 	Topic       string
 	Channel     string
 	MaxInFlight int
-	parser      parsers.Parser
-	consumer    *nsq.Consumer
-	acc         telegraf.Accumulator
+	// Maximum number of messages whose metrics are not yet written by the
+	// outputs. Messages are finished once their metrics are written.
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+	// Interval at which the undelivered messages are touched, so that nsqd
+	// does not requeue them while the outputs are unavailable.
+	TouchInterval internal.Duration `toml:"touch_interval"`
+
+	parser   parsers.Parser
+	consumer *nsq.Consumer
+	acc      telegraf.TrackingAccumulator
+
+	// sem holds a value for each undelivered message.
+	sem  chan struct{}
+	done chan struct{}
+	wg   sync.WaitGroup
+
+	mu          sync.Mutex
+	undelivered map[telegraf.TrackingID]*nsq.Message
+	// delivered holds the delivery of the groups reported before their
+	// message is recorded as undelivered.
+	delivered map[telegraf.TrackingID]bool
 }
 
+const defaultMaxUndeliveredMessages = 1000
+
+// Default interval at which the undelivered messages are touched, half of the
+// default msg_timeout of nsqd.
+const defaultTouchInterval = 30 * time.Second
+
 var sampleConfig = `
   ## An string representing the NSQD TCP Endpoint
   server = "localhost:4150"
@@ -27,6 +54,17 @@ var sampleConfig = `
   channel = "consumer"
   max_in_flight = 100
 
+  ## Maximum number of messages whose metrics are not yet written by the
+  ## outputs. Messages are only finished once their metrics are written, the
+  ## consumer pauses when this many messages are undelivered.
+  # max_undelivered_messages = 1000
+
+  ## Interval at which the undelivered messages are touched, to reset their
+  ## timeout in nsqd. It must be shorter than the msg_timeout of nsqd, the
+  ## messages are otherwise requeued and delivered again while the outputs
+  ## are unavailable.
+  # touch_interval = "30s"
+
   ## Data format to consume.
   ## Each data format has its own unique set of configuration options, read
   ## more about them here:
@@ -36,7 +74,10 @@ var sampleConfig = `
 
 func init() {
 	inputs.Add("nsq_consumer", func() telegraf.Input {
-		return &NSQConsumer{}
+		return &NSQConsumer{
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+			TouchInterval:          internal.Duration{Duration: defaultTouchInterval},
+		}
 	})
 }
 
@@ -57,27 +98,118 @@ func (n *NSQConsumer) Description() string {
 
 // Start pulls data from nsq
 func (n *NSQConsumer) Start(acc telegraf.Accumulator) error {
-	n.acc = acc
+	if n.MaxUndeliveredMessages < 1 {
+		n.MaxUndeliveredMessages = defaultMaxUndeliveredMessages
+	}
+	if n.TouchInterval.Duration <= 0 {
+		n.TouchInterval.Duration = defaultTouchInterval
+	}
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
+	n.sem = make(chan struct{}, n.MaxUndeliveredMessages)
+	n.done = make(chan struct{})
+	n.undelivered = make(map[telegraf.TrackingID]*nsq.Message)
+	n.delivered = make(map[telegraf.TrackingID]bool)
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		n.onDelivery()
+	}()
+
 	n.connect()
-	n.consumer.AddConcurrentHandlers(nsq.HandlerFunc(func(message *nsq.Message) error {
-		metrics, err := n.parser.Parse(message.Body)
-		if err != nil {
-			acc.AddError(fmt.Errorf("E! NSQConsumer Parse Error\nmessage:%s\nerror:%s", string(message.Body), err.Error()))
-			return nil
-		}
-		for _, metric := range metrics {
-			n.acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
-		}
-		message.Finish()
-		return nil
-	}), n.MaxInFlight)
+	n.consumer.AddConcurrentHandlers(nsq.HandlerFunc(n.handleMessage),
+		n.MaxInFlight)
 	n.consumer.ConnectToNSQD(n.Server)
 	return nil
 }
 
-// Stop processing messages
+// handleMessage adds the metrics of a message. The message is finished once
+// they are written by the outputs, and requeued if they are lost.
+func (n *NSQConsumer) handleMessage(message *nsq.Message) error {
+	metrics, err := n.parser.Parse(message.Body)
+	if err != nil {
+		n.acc.AddError(fmt.Errorf("E! NSQConsumer Parse Error\nmessage:%s\nerror:%s", string(message.Body), err.Error()))
+		return nil
+	}
+
+	message.DisableAutoResponse()
+	select {
+	case n.sem <- struct{}{}:
+	case <-n.done:
+		// nsqd requeues the message once it times out.
+		return nil
+	}
+
+	// the lock is not held while the metrics are added, it blocks until the
+	// agent takes them and they may be delivered before they are recorded.
+	id := n.acc.AddTrackingMetricGroup(metrics)
+	n.mu.Lock()
+	delivered, ok := n.delivered[id]
+	if !ok {
+		n.undelivered[id] = message
+		n.mu.Unlock()
+		return nil
+	}
+	delete(n.delivered, id)
+	n.mu.Unlock()
+
+	n.respond(message, delivered)
+	return nil
+}
+
+// respond finishes the message if its metrics were delivered and requeues it
+// otherwise.
+func (n *NSQConsumer) respond(message *nsq.Message, delivered bool) {
+	if delivered {
+		message.Finish()
+	} else {
+		message.Requeue(-1)
+	}
+	<-n.sem
+}
+
+// onDelivery finishes or requeues the messages as their delivery is reported,
+// and touches the undelivered messages, until the consumer is stopped.
+func (n *NSQConsumer) onDelivery() {
+	ticker := time.NewTicker(n.TouchInterval.Duration)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.done:
+			return
+		case <-ticker.C:
+			n.mu.Lock()
+			messages := make([]*nsq.Message, 0, len(n.undelivered))
+			for _, message := range n.undelivered {
+				messages = append(messages, message)
+			}
+			n.mu.Unlock()
+			for _, message := range messages {
+				message.Touch()
+			}
+		case track := <-n.acc.Delivered():
+			n.mu.Lock()
+			message, ok := n.undelivered[track.ID()]
+			if !ok {
+				n.delivered[track.ID()] = track.Delivered()
+				n.mu.Unlock()
+				continue
+			}
+			delete(n.undelivered, track.ID())
+			n.mu.Unlock()
+
+			n.respond(message, track.Delivered())
+		}
+	}
+}
+
+// Stop processing messages. The consumer is not waited for, the undelivered
+// messages are left unfinished and requeued by nsqd once they time out.
 func (n *NSQConsumer) Stop() {
+	// the handlers waiting for undelivered messages return first.
+	close(n.done)
 	n.consumer.Stop()
+	n.wg.Wait()
 }
 
 // Gather is a noop
@@ -1,6 +1,7 @@
 package prometheus
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -66,6 +67,14 @@ var ErrProtocolError = errors.New("prometheus protocol error")
 // Reads stats from all configured servers accumulates stats.
 // Returns one of the errors encountered while gather stats (if any).
 func (p *Prometheus) Gather(acc telegraf.Accumulator) error {
+	return p.GatherContext(context.Background(), acc)
+}
+
+// GatherContext is Gather, cancelling the requests when ctx is done.
+func (p *Prometheus) GatherContext(
+	ctx context.Context,
+	acc telegraf.Accumulator,
+) error {
 	if p.client == nil {
 		client, err := p.createHttpClient()
 		if err != nil {
@@ -80,7 +89,7 @@ func (p *Prometheus) Gather(acc telegraf.Accumulator) error {
 		wg.Add(1)
 		go func(serv string) {
 			defer wg.Done()
-			acc.AddError(p.gatherURL(serv, acc))
+			acc.AddError(p.gatherURL(ctx, serv, acc))
 		}(serv)
 	}
 
@@ -116,8 +125,16 @@ func (p *Prometheus) createHttpClient() (*http.Client, error) {
 	return client, nil
 }
 
-func (p *Prometheus) gatherURL(url string, acc telegraf.Accumulator) error {
+func (p *Prometheus) gatherURL(
+	ctx context.Context,
+	url string,
+	acc telegraf.Accumulator,
+) error {
 	var req, err = http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
 	req.Header.Add("Accept", acceptHeader)
 	var token []byte
 	var resp *http.Response
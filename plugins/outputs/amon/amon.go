@@ -2,6 +2,7 @@ package amon
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -54,6 +55,14 @@ func (a *Amon) Connect() error {
 }
 
 func (a *Amon) Write(metrics []telegraf.Metric) error {
+	return a.WriteContext(context.Background(), metrics)
+}
+
+// WriteContext is Write, cancelling the requests when ctx is done.
+func (a *Amon) WriteContext(
+	ctx context.Context,
+	metrics []telegraf.Metric,
+) error {
 	if len(metrics) == 0 {
 		return nil
 	}
@@ -89,7 +98,7 @@ func (a *Amon) Write(metrics []telegraf.Metric) error {
 	}
 	req.Header.Add("Content-Type", "application/json")
 
-	resp, err := a.client.Do(req)
+	resp, err := a.client.Do(req.WithContext(ctx))
 	if err != nil {
 		return fmt.Errorf("error POSTing metrics, %s\n", err.Error())
 	}
@@ -2,6 +2,7 @@ package datadog
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -62,6 +63,14 @@ func (d *Datadog) Connect() error {
 }
 
 func (d *Datadog) Write(metrics []telegraf.Metric) error {
+	return d.WriteContext(context.Background(), metrics)
+}
+
+// WriteContext is Write, cancelling the requests when ctx is done.
+func (d *Datadog) WriteContext(
+	ctx context.Context,
+	metrics []telegraf.Metric,
+) error {
 	if len(metrics) == 0 {
 		return nil
 	}
@@ -108,7 +117,7 @@ func (d *Datadog) Write(metrics []telegraf.Metric) error {
 	}
 	req.Header.Add("Content-Type", "application/json")
 
-	resp, err := d.client.Do(req)
+	resp, err := d.client.Do(req.WithContext(ctx))
 	if err != nil {
 		return fmt.Errorf("error POSTing metrics, %s\n", err.Error())
 	}
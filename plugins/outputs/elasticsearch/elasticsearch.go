@@ -129,6 +129,14 @@ func (a *Elasticsearch) Connect() error {
 }
 
 func (a *Elasticsearch) Write(metrics []telegraf.Metric) error {
+	return a.WriteContext(context.Background(), metrics)
+}
+
+// WriteContext is Write, cancelling the bulk request when ctx is done.
+func (a *Elasticsearch) WriteContext(
+	ctx context.Context,
+	metrics []telegraf.Metric,
+) error {
 	if len(metrics) == 0 {
 		return nil
 	}
@@ -156,7 +164,7 @@ func (a *Elasticsearch) Write(metrics []telegraf.Metric) error {
 
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), a.Timeout.Duration)
+	ctx, cancel := context.WithTimeout(ctx, a.Timeout.Duration)
 	defer cancel()
 
 	res, err := bulkRequest.Do(ctx)
@@ -1,6 +1,9 @@
 package client
 
-import "io"
+import (
+	"context"
+	"io"
+)
 
 type Client interface {
 	Query(command string) error
@@ -9,6 +12,8 @@ type Client interface {
 	WriteWithParams(b []byte, params WriteParams) (int, error)
 
 	WriteStream(b io.Reader, contentLength int) (int, error)
+	// WriteStreamContext is WriteStream, cancelled with ctx.
+	WriteStreamContext(ctx context.Context, b io.Reader, contentLength int) (int, error)
 	WriteStreamWithParams(b io.Reader, contentLength int, params WriteParams) (int, error)
 
 	Close() error
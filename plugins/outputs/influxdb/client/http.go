@@ -2,6 +2,7 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
@@ -139,12 +140,21 @@ func (c *httpClient) WriteWithParams(b []byte, wp WriteParams) (int, error) {
 }
 
 func (c *httpClient) WriteStream(r io.Reader, contentLength int) (int, error) {
+	return c.WriteStreamContext(context.Background(), r, contentLength)
+}
+
+// WriteStreamContext is WriteStream, cancelling the request when ctx is done.
+func (c *httpClient) WriteStreamContext(
+	ctx context.Context,
+	r io.Reader,
+	contentLength int,
+) (int, error) {
 	req, err := c.makeWriteRequest(r, contentLength, c.writeURL)
 	if err != nil {
 		return 0, nil
 	}
 
-	err = c.doRequest(req, http.StatusNoContent)
+	err = c.doRequest(req.WithContext(ctx), http.StatusNoContent)
 	if err == nil {
 		return contentLength, nil
 	}
@@ -2,6 +2,7 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -206,6 +207,37 @@ func TestHTTPClient_Write_Errors(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestHTTPClient_WriteStreamContext(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	config := HTTPConfig{
+		URL: ts.URL,
+	}
+	defaultWP := WriteParams{
+		Database: "test",
+	}
+	client, err := NewHTTP(config, defaultWP)
+	defer client.Close()
+	assert.NoError(t, err)
+
+	lp := []byte("cpu value=99\n")
+	n, err := client.WriteStreamContext(context.Background(), bytes.NewReader(lp), 13)
+	assert.Equal(t, 13, n)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	n, err = client.WriteStreamContext(ctx, bytes.NewReader(lp), 13)
+	assert.Equal(t, 0, n)
+	assert.Error(t, err)
+	assert.Equal(t, 1, requests)
+}
+
 func TestNewHTTPErrors(t *testing.T) {
 	// No URL:
 	config := HTTPConfig{}
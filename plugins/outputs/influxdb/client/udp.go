@@ -2,6 +2,7 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"log"
@@ -110,6 +111,11 @@ func (c *udpClient) WriteStream(r io.Reader, contentLength int) (int, error) {
 	return totaln, nil
 }
 
+// WriteStreamContext will forward the stream to WriteStream, the UDP writes are not cancelled
+func (c *udpClient) WriteStreamContext(ctx context.Context, r io.Reader, contentLength int) (int, error) {
+	return c.WriteStream(r, contentLength)
+}
+
 // WriteStreamWithParams will forward the stream to the client backend, contentLength is ignored by the UDP client
 // write params are ignored by the UDP client
 func (c *udpClient) WriteStreamWithParams(r io.Reader, contentLength int, wp WriteParams) (int, error) {
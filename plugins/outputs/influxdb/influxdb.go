@@ -1,6 +1,7 @@
 package influxdb
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"math/rand"
@@ -167,7 +168,14 @@ func (i *InfluxDB) Description() string {
 // Write will choose a random server in the cluster to write to until a successful write
 // occurs, logging each unsuccessful. If all servers fail, return error.
 func (i *InfluxDB) Write(metrics []telegraf.Metric) error {
+	return i.WriteContext(context.Background(), metrics)
+}
 
+// WriteContext is Write, cancelling the requests when ctx is done.
+func (i *InfluxDB) WriteContext(
+	ctx context.Context,
+	metrics []telegraf.Metric,
+) error {
 	bufsize := 0
 	for _, m := range metrics {
 		bufsize += m.Len()
@@ -180,7 +188,11 @@ func (i *InfluxDB) Write(metrics []telegraf.Metric) error {
 
 	p := rand.Perm(len(i.clients))
 	for _, n := range p {
-		if _, e := i.clients[n].WriteStream(r, bufsize); e != nil {
+		// the other servers are not tried once cancelled.
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if _, e := i.clients[n].WriteStreamContext(ctx, r, bufsize); e != nil {
 			// If the database was not found, try to recreate it:
 			if strings.Contains(e.Error(), "database not found") {
 				errc := i.clients[n].Query(fmt.Sprintf(`CREATE DATABASE "%s"`, qiReplacer.Replace(i.Database)))
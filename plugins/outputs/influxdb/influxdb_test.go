@@ -1,6 +1,7 @@
 package influxdb
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -225,6 +226,10 @@ func (m *MockClient) WriteStream(b io.Reader, contentLength int) (int, error) {
 	return 0, nil
 }
 
+func (m *MockClient) WriteStreamContext(ctx context.Context, b io.Reader, contentLength int) (int, error) {
+	return m.WriteStream(b, contentLength)
+}
+
 func (m *MockClient) WriteStreamWithParams(b io.Reader, contentLength int, params client.WriteParams) (int, error) {
 	panic("not implemented")
 }
@@ -2,6 +2,7 @@ package librato
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -86,6 +87,14 @@ func (l *Librato) Connect() error {
 }
 
 func (l *Librato) Write(metrics []telegraf.Metric) error {
+	return l.WriteContext(context.Background(), metrics)
+}
+
+// WriteContext is Write, cancelling the requests when ctx is done.
+func (l *Librato) WriteContext(
+	ctx context.Context,
+	metrics []telegraf.Metric,
+) error {
 
 	if len(metrics) == 0 {
 		return nil
@@ -144,7 +153,7 @@ func (l *Librato) Write(metrics []telegraf.Metric) error {
 		req.Header.Add("Content-Type", "application/json")
 		req.SetBasicAuth(l.APIUser, l.APIToken)
 
-		resp, err := l.client.Do(req)
+		resp, err := l.client.Do(req.WithContext(ctx))
 		if err != nil {
 			log.Printf("D! Error POSTing metrics: %v\n", err.Error())
 			return fmt.Errorf("error POSTing metrics, %s\n", err.Error())
@@ -1,6 +1,7 @@
 package opentsdb
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net"
@@ -82,6 +83,15 @@ func (o *OpenTSDB) Connect() error {
 }
 
 func (o *OpenTSDB) Write(metrics []telegraf.Metric) error {
+	return o.WriteContext(context.Background(), metrics)
+}
+
+// WriteContext is Write, cancelling the requests of the HTTP API when ctx is
+// done.
+func (o *OpenTSDB) WriteContext(
+	ctx context.Context,
+	metrics []telegraf.Metric,
+) error {
 	if len(metrics) == 0 {
 		return nil
 	}
@@ -94,13 +104,17 @@ func (o *OpenTSDB) Write(metrics []telegraf.Metric) error {
 	if u.Scheme == "" || u.Scheme == "tcp" {
 		return o.WriteTelnet(metrics, u)
 	} else if u.Scheme == "http" || u.Scheme == "https" {
-		return o.WriteHttp(metrics, u)
+		return o.WriteHttp(ctx, metrics, u)
 	} else {
 		return fmt.Errorf("Unknown scheme in host parameter.")
 	}
 }
 
-func (o *OpenTSDB) WriteHttp(metrics []telegraf.Metric, u *url.URL) error {
+func (o *OpenTSDB) WriteHttp(
+	ctx context.Context,
+	metrics []telegraf.Metric,
+	u *url.URL,
+) error {
 	http := openTSDBHttp{
 		Host:      u.Host,
 		Port:      o.Port,
@@ -132,13 +146,13 @@ func (o *OpenTSDB) WriteHttp(metrics []telegraf.Metric, u *url.URL) error {
 				Value:     value,
 			}
 
-			if err := http.sendDataPoint(metric); err != nil {
+			if err := http.sendDataPoint(ctx, metric); err != nil {
 				return err
 			}
 		}
 	}
 
-	if err := http.flush(); err != nil {
+	if err := http.flush(ctx); err != nil {
 		return err
 	}
 
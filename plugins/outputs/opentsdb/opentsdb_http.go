@@ -3,6 +3,7 @@ package opentsdb
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -91,7 +92,7 @@ func (r *requestBody) close() error {
 	return nil
 }
 
-func (o *openTSDBHttp) sendDataPoint(metric *HttpMetric) error {
+func (o *openTSDBHttp) sendDataPoint(ctx context.Context, metric *HttpMetric) error {
 	if o.metricCounter == 0 {
 		o.body.reset(o.Debug)
 	}
@@ -102,7 +103,7 @@ func (o *openTSDBHttp) sendDataPoint(metric *HttpMetric) error {
 
 	o.metricCounter++
 	if o.metricCounter == o.BatchSize {
-		if err := o.flush(); err != nil {
+		if err := o.flush(ctx); err != nil {
 			return err
 		}
 
@@ -112,7 +113,7 @@ func (o *openTSDBHttp) sendDataPoint(metric *HttpMetric) error {
 	return nil
 }
 
-func (o *openTSDBHttp) flush() error {
+func (o *openTSDBHttp) flush(ctx context.Context) error {
 	if o.metricCounter == 0 {
 		return nil
 	}
@@ -147,7 +148,7 @@ func (o *openTSDBHttp) flush() error {
 		fmt.Printf("Body:\n%s\n\n", o.body.dbgB.String())
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
 	if err != nil {
 		return fmt.Errorf("Error when sending metrics: %s", err.Error())
 	}
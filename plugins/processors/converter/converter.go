@@ -0,0 +1,452 @@
+package converter
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// Conversion lists, by type, the patterns of the keys to convert.
+type Conversion struct {
+	Tag      []string `toml:"tag"`
+	String   []string `toml:"string"`
+	Integer  []string `toml:"integer"`
+	Unsigned []string `toml:"unsigned"`
+	Boolean  []string `toml:"boolean"`
+	Float    []string `toml:"float"`
+}
+
+type Converter struct {
+	Tags   *Conversion `toml:"tags"`
+	Fields *Conversion `toml:"fields"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	tagConversions   []conversion
+	fieldConversions []conversion
+	conversionErrors selfstat.Stat
+}
+
+// Types the values are converted to.
+const (
+	typeTag = iota
+	typeString
+	typeInteger
+	typeUnsigned
+	typeBoolean
+	typeFloat
+)
+
+// conversion converts the keys matching filter to typ.
+type conversion struct {
+	typ    int
+	filter filter.Filter
+}
+
+var sampleConfig = `
+  ## Tags to convert to fields of the given type. The keys are matched by
+  ## name or by glob pattern.
+  [processors.converter.tags]
+    string = []
+    integer = []
+    unsigned = []
+    boolean = []
+    float = []
+
+  ## Fields to convert to tags, or to the given type. Strings are parsed
+  ## leniently: "0x1F" is parsed as 31, "1.5k" as 1500 and "2Ki" as 2048.
+  [processors.converter.fields]
+    tag = []
+    string = []
+    integer = []
+    unsigned = []
+    boolean = []
+    float = []
+`
+
+func (c *Converter) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *Converter) Description() string {
+	return "Convert values to another metric value type"
+}
+
+func (c *Converter) Init(tags map[string]string) error {
+	c.conversionErrors = selfstat.Register("converter", "conversion_errors",
+		tags)
+
+	var err error
+	if c.Tags != nil {
+		// tags are already tags, the tag list of the tags is ignored.
+		conv := *c.Tags
+		conv.Tag = nil
+		c.tagConversions, err = compileConversions(&conv)
+		if err != nil {
+			return fmt.Errorf("invalid tag conversions: %s", err)
+		}
+	}
+	if c.Fields != nil {
+		c.fieldConversions, err = compileConversions(c.Fields)
+		if err != nil {
+			return fmt.Errorf("invalid field conversions: %s", err)
+		}
+	}
+	return nil
+}
+
+func (c *Converter) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, metric := range in {
+		c.convertTags(metric)
+		c.convertFields(metric)
+	}
+	return in
+}
+
+// compileConversions returns the conversions of conv, in order of precedence
+// when a key matches the patterns of several types.
+func compileConversions(conv *Conversion) ([]conversion, error) {
+	var conversions []conversion
+	for typ, patterns := range [][]string{
+		typeTag:      conv.Tag,
+		typeString:   conv.String,
+		typeInteger:  conv.Integer,
+		typeUnsigned: conv.Unsigned,
+		typeBoolean:  conv.Boolean,
+		typeFloat:    conv.Float,
+	} {
+		f, err := filter.Compile(patterns)
+		if err != nil {
+			return nil, err
+		}
+		if f != nil {
+			conversions = append(conversions, conversion{typ: typ, filter: f})
+		}
+	}
+	return conversions, nil
+}
+
+// match returns the type key is converted to, and false if it is not
+// converted.
+func match(conversions []conversion, key string) (int, bool) {
+	for _, conv := range conversions {
+		if conv.filter.Match(key) {
+			return conv.typ, true
+		}
+	}
+	return 0, false
+}
+
+// convertTags converts the matching tags to fields. Tags whose value cannot
+// be converted are left unchanged.
+func (c *Converter) convertTags(metric telegraf.Metric) {
+	if len(c.tagConversions) == 0 {
+		return
+	}
+	for key, value := range metric.Tags() {
+		typ, ok := match(c.tagConversions, key)
+		if !ok {
+			continue
+		}
+		v, err := convert(typ, value)
+		if err != nil {
+			c.failed(metric, key, value, err)
+			continue
+		}
+		metric.RemoveTag(key)
+		metric.AddField(key, v)
+	}
+}
+
+// convertFields converts the matching fields to tags or to another type.
+// Fields whose value cannot be converted are left unchanged.
+func (c *Converter) convertFields(metric telegraf.Metric) {
+	if len(c.fieldConversions) == 0 {
+		return
+	}
+	for key, value := range metric.Fields() {
+		typ, ok := match(c.fieldConversions, key)
+		if !ok {
+			continue
+		}
+		if typ == typeTag {
+			s := toString(value)
+			if err := metric.RemoveField(key); err != nil {
+				c.failed(metric, key, value, err)
+				continue
+			}
+			metric.AddTag(key, s)
+			continue
+		}
+		v, err := convert(typ, value)
+		if err != nil {
+			c.failed(metric, key, value, err)
+			continue
+		}
+		metric.AddField(key, v)
+	}
+}
+
+// failed records a value that could not be converted.
+func (c *Converter) failed(metric telegraf.Metric, key string, value interface{}, err error) {
+	c.conversionErrors.Incr(1)
+	c.Log.Debugf("Unable to convert %q of %s with value %v: %s",
+		key, metric.Name(), value, err)
+}
+
+// convert returns value converted to typ.
+func convert(typ int, value interface{}) (interface{}, error) {
+	switch typ {
+	case typeString:
+		return toString(value), nil
+	case typeInteger:
+		return toInteger(value)
+	case typeUnsigned:
+		return toUnsigned(value)
+	case typeBoolean:
+		return toBoolean(value)
+	case typeFloat:
+		return toFloat(value)
+	}
+	return nil, fmt.Errorf("unknown type %d", typ)
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(value)
+}
+
+func toInteger(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return nil, fmt.Errorf("%d overflows an integer", v)
+		}
+		return int64(v), nil
+	case float64:
+		return floatToInteger(v)
+	case bool:
+		if v {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case string:
+		return parseInteger(v)
+	}
+	return nil, fmt.Errorf("unsupported type %T", value)
+}
+
+func toUnsigned(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case int64:
+		if v < 0 {
+			return nil, fmt.Errorf("%d is negative", v)
+		}
+		return uint64(v), nil
+	case uint64:
+		return v, nil
+	case float64:
+		if v < 0 {
+			return nil, fmt.Errorf("%v is negative", v)
+		}
+		if math.IsNaN(v) || v >= math.MaxUint64 {
+			return nil, fmt.Errorf("%v overflows an unsigned integer", v)
+		}
+		return uint64(v), nil
+	case bool:
+		if v {
+			return uint64(1), nil
+		}
+		return uint64(0), nil
+	case string:
+		return parseUnsigned(v)
+	}
+	return nil, fmt.Errorf("unsupported type %T", value)
+}
+
+func toBoolean(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case int64:
+		return v != 0, nil
+	case uint64:
+		return v != 0, nil
+	case float64:
+		return v != 0, nil
+	case string:
+		return parseBoolean(v)
+	}
+	return nil, fmt.Errorf("unsupported type %T", value)
+}
+
+func toFloat(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case bool:
+		if v {
+			return float64(1), nil
+		}
+		return float64(0), nil
+	case string:
+		return parseFloat(v)
+	}
+	return nil, fmt.Errorf("unsupported type %T", value)
+}
+
+// floatToInteger truncates f toward zero.
+func floatToInteger(f float64) (int64, error) {
+	if math.IsNaN(f) || f >= math.MaxInt64 || f < math.MinInt64 {
+		return 0, fmt.Errorf("%v overflows an integer", f)
+	}
+	return int64(f), nil
+}
+
+// multipliers are the suffixes accepted by parseFloat.
+var multipliers = map[string]float64{
+	"k":  1e3,
+	"K":  1e3,
+	"M":  1e6,
+	"G":  1e9,
+	"T":  1e12,
+	"P":  1e15,
+	"Ki": 1 << 10,
+	"Mi": 1 << 20,
+	"Gi": 1 << 30,
+	"Ti": 1 << 40,
+	"Pi": 1 << 50,
+}
+
+// parseFloat parses s leniently: surrounding spaces are ignored, integers may
+// be hexadecimal, eg. "0x1F", and numbers may have a decimal or binary
+// multiplier suffix, eg. "1.5k" or "2Ki".
+func parseFloat(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		// not numbers as far as the line protocol is concerned
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, fmt.Errorf("unable to parse %q as a number", s)
+		}
+		return f, nil
+	}
+	if i, err := parseHex(s); err == nil {
+		return float64(i), nil
+	}
+	for _, n := range []int{2, 1} {
+		if len(s) <= n {
+			continue
+		}
+		if mult, ok := multipliers[s[len(s)-n:]]; ok {
+			f, err := strconv.ParseFloat(strings.TrimSpace(s[:len(s)-n]), 64)
+			if err != nil {
+				break
+			}
+			return f * mult, nil
+		}
+	}
+	return 0, fmt.Errorf("unable to parse %q as a number", s)
+}
+
+// parseHex parses s as a possibly negative hexadecimal integer prefixed by
+// "0x".
+func parseHex(s string) (int64, error) {
+	neg := strings.HasPrefix(s, "-")
+	digits := strings.TrimPrefix(s, "-")
+	if !strings.HasPrefix(digits, "0x") && !strings.HasPrefix(digits, "0X") {
+		return 0, fmt.Errorf("%q is not hexadecimal", s)
+	}
+	i, err := strconv.ParseInt(digits[2:], 16, 64)
+	if err != nil {
+		return 0, err
+	}
+	if neg {
+		i = -i
+	}
+	return i, nil
+}
+
+func parseInteger(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, nil
+	}
+	if i, err := parseHex(s); err == nil {
+		return i, nil
+	}
+	f, err := parseFloat(s)
+	if err != nil {
+		return 0, err
+	}
+	return floatToInteger(f)
+}
+
+func parseUnsigned(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return u, nil
+	}
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		return strconv.ParseUint(s[2:], 16, 64)
+	}
+	f, err := parseFloat(s)
+	if err != nil {
+		return 0, err
+	}
+	if f < 0 {
+		return 0, fmt.Errorf("%q is negative", s)
+	}
+	if f >= math.MaxUint64 {
+		return 0, fmt.Errorf("%q overflows an unsigned integer", s)
+	}
+	return uint64(f), nil
+}
+
+// parseBoolean parses s as a boolean, accepting "yes", "no", "on" and "off"
+// in addition to the values accepted by strconv.ParseBool, and numbers.
+func parseBoolean(s string) (bool, error) {
+	s = strings.TrimSpace(s)
+	switch strings.ToLower(s) {
+	case "yes", "on":
+		return true, nil
+	case "no", "off":
+		return false, nil
+	}
+	if b, err := strconv.ParseBool(s); err == nil {
+		return b, nil
+	}
+	f, err := parseFloat(s)
+	if err != nil {
+		return false, fmt.Errorf("unable to parse %q as a boolean", s)
+	}
+	return f != 0, nil
+}
+
+func init() {
+	processors.Add("converter", func() telegraf.Processor {
+		return &Converter{}
+	})
+}
@@ -0,0 +1,219 @@
+package converter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertTagsToFields(t *testing.T) {
+	c := &Converter{
+		Tags: &Conversion{
+			String:   []string{"name"},
+			Integer:  []string{"port"},
+			Unsigned: []string{"id"},
+			Boolean:  []string{"up"},
+			Float:    []string{"load_*"},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, c.Init(nil))
+
+	m, err := metric.New("cpu",
+		map[string]string{
+			"name":   "web",
+			"port":   "8080",
+			"id":     "0x10",
+			"up":     "yes",
+			"load_1": "1.5k",
+			"host":   "localhost",
+		},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(0, 0),
+	)
+	require.NoError(t, err)
+	results := c.Apply(m)
+	require.Len(t, results, 1)
+	assert.Equal(t, map[string]string{"host": "localhost"}, results[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"value":  1.0,
+		"name":   "web",
+		"port":   int64(8080),
+		"id":     int64(16),
+		"up":     true,
+		"load_1": 1500.0,
+	}, results[0].Fields())
+}
+
+func TestConvertFields(t *testing.T) {
+	c := &Converter{
+		Fields: &Conversion{
+			Tag:      []string{"state"},
+			String:   []string{"code"},
+			Integer:  []string{"int_*"},
+			Unsigned: []string{"uint"},
+			Boolean:  []string{"bool"},
+			Float:    []string{"float"},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, c.Init(nil))
+
+	m, err := metric.New("cpu",
+		map[string]string{},
+		map[string]interface{}{
+			"state":     "running",
+			"code":      int64(200),
+			"int_hex":   "0x1F",
+			"int_float": 2.9,
+			"int_si":    "2Ki",
+			"uint":      "42",
+			"bool":      int64(0),
+			"float":     "3.25",
+		},
+		time.Unix(0, 0),
+	)
+	require.NoError(t, err)
+	results := c.Apply(m)
+	require.Len(t, results, 1)
+	assert.Equal(t, map[string]string{"state": "running"}, results[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"code":      "200",
+		"int_hex":   int64(31),
+		"int_float": int64(2),
+		"int_si":    int64(2048),
+		"uint":      int64(42),
+		"bool":      false,
+		"float":     3.25,
+	}, results[0].Fields())
+}
+
+func TestConversionErrors(t *testing.T) {
+	c := &Converter{
+		Tags: &Conversion{
+			Integer: []string{"port"},
+		},
+		Fields: &Conversion{
+			Tag:      []string{"value"},
+			Integer:  []string{"bad_int"},
+			Unsigned: []string{"negative"},
+			Float:    []string{"nan"},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, c.Init(map[string]string{"processor": "converter"}))
+	errors := c.conversionErrors.Get()
+
+	m, err := metric.New("cpu",
+		map[string]string{"port": "http"},
+		map[string]interface{}{
+			"value":    1.0,
+			"bad_int":  "12 apples",
+			"negative": int64(-1),
+			"nan":      "NaN",
+		},
+		time.Unix(0, 0),
+	)
+	require.NoError(t, err)
+	results := c.Apply(m)
+	require.Len(t, results, 1)
+
+	// the values that cannot be converted are left unchanged
+	assert.Equal(t, map[string]string{"port": "http", "value": "1"}, results[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"bad_int":  "12 apples",
+		"negative": int64(-1),
+		"nan":      "NaN",
+	}, results[0].Fields())
+	assert.Equal(t, errors+4, c.conversionErrors.Get())
+	assert.Equal(t, map[string]string{"processor": "converter"},
+		c.conversionErrors.Tags())
+
+	// the last field cannot become a tag
+	m, err = metric.New("cpu",
+		map[string]string{},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(0, 0),
+	)
+	require.NoError(t, err)
+	results = c.Apply(m)
+	require.Len(t, results, 1)
+	assert.Equal(t, map[string]interface{}{"value": 1.0}, results[0].Fields())
+	assert.Equal(t, errors+5, c.conversionErrors.Get())
+}
+
+func TestConversionPrecedence(t *testing.T) {
+	c := &Converter{
+		Fields: &Conversion{
+			String:  []string{"*"},
+			Integer: []string{"count"},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, c.Init(nil))
+
+	m, err := metric.New("cpu",
+		map[string]string{},
+		map[string]interface{}{"count": "10"},
+		time.Unix(0, 0),
+	)
+	require.NoError(t, err)
+	results := c.Apply(m)
+	require.Len(t, results, 1)
+	assert.Equal(t, map[string]interface{}{"count": "10"}, results[0].Fields())
+}
+
+func TestInvalidConversion(t *testing.T) {
+	c := &Converter{
+		Fields: &Conversion{
+			Integer: []string{"count["},
+		},
+		Log: testutil.Logger{},
+	}
+	assert.Error(t, c.Init(nil))
+}
+
+func TestParseFloat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+		err      bool
+	}{
+		{input: "1.5", expected: 1.5},
+		{input: " 42 ", expected: 42},
+		{input: "-0x10", expected: -16},
+		{input: "1.5k", expected: 1500},
+		{input: "2 M", expected: 2e6},
+		{input: "1Gi", expected: 1 << 30},
+		{input: "1e3", expected: 1000},
+		{input: "Inf", err: true},
+		{input: "k", err: true},
+		{input: "12 apples", err: true},
+	}
+	for _, tt := range tests {
+		f, err := parseFloat(tt.input)
+		if tt.err {
+			assert.Error(t, err, tt.input)
+			continue
+		}
+		require.NoError(t, err, tt.input)
+		assert.Equal(t, tt.expected, f, tt.input)
+	}
+}
+
+func TestParseBoolean(t *testing.T) {
+	for input, expected := range map[string]bool{
+		"true": true, "FALSE": false, "yes": true, "Off": false, "1": true, "0.0": false,
+	} {
+		b, err := parseBoolean(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, expected, b, input)
+	}
+	_, err := parseBoolean("maybe")
+	assert.Error(t, err)
+}
@@ -0,0 +1,145 @@
+package enum
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+type EnumMapper struct {
+	Mappings []mapping `toml:"mapping"`
+
+	filters []filter.Filter
+}
+
+// mapping maps the string values of the tags or fields matching Tag or Field
+// through ValueMappings.
+type mapping struct {
+	Tag   string
+	Field string
+	// Dest is the key the mapped value is written to, the key of the value
+	// by default.
+	Dest string
+	// Default is the value of the values missing from ValueMappings, which
+	// are left unchanged when not set.
+	Default       interface{}
+	ValueMappings map[string]interface{}
+}
+
+var sampleConfig = `
+  ## Mappings are applied in order. Each one maps the string values of either
+  ## a tag or a field, matched by name or by glob pattern.
+  # [[processors.enum.mapping]]
+  #   ## Name of the field to map
+  #   field = "status"
+  #
+  #   ## Name of the key to write the mapped value to, by default the value of
+  #   ## the field is replaced.
+  #   # dest = "status_code"
+  #
+  #   ## Value of the values missing from the table, when not set these
+  #   ## values are left unchanged.
+  #   # default = 0
+  #
+  #   ## Table of mappings
+  #   [processors.enum.mapping.value_mappings]
+  #     UP = 1
+  #     DOWN = 0
+  #     MAINT = 2
+  #
+  # [[processors.enum.mapping]]
+  #   ## Name of the tag to map, mapped values are written as strings.
+  #   tag = "mode"
+  #   [processors.enum.mapping.value_mappings]
+  #     client = "3"
+  #     server = "4"
+`
+
+func (e *EnumMapper) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *EnumMapper) Description() string {
+	return "Map enum values according to given table."
+}
+
+func (e *EnumMapper) Init(tags map[string]string) error {
+	e.filters = make([]filter.Filter, len(e.Mappings))
+	for i, mapping := range e.Mappings {
+		pattern := mapping.Field
+		if mapping.Tag != "" {
+			pattern = mapping.Tag
+		}
+		if pattern == "" {
+			return fmt.Errorf("mapping %d requires one of tag or field", i+1)
+		}
+		f, err := filter.Compile([]string{pattern})
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q of mapping %d: %s",
+				pattern, i+1, err)
+		}
+		e.filters[i] = f
+	}
+	return nil
+}
+
+func (e *EnumMapper) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, metric := range in {
+		for i, mapping := range e.Mappings {
+			f := e.filters[i]
+			if mapping.Tag != "" {
+				for key, value := range metric.Tags() {
+					if !f.Match(key) {
+						continue
+					}
+					if mapped, ok := mapping.mapValue(value); ok {
+						metric.AddTag(mapping.destKey(key), fmt.Sprint(mapped))
+					}
+				}
+				continue
+			}
+			for key, value := range metric.Fields() {
+				if !f.Match(key) {
+					continue
+				}
+				// only string fields are mapped
+				s, ok := value.(string)
+				if !ok {
+					continue
+				}
+				if mapped, ok := mapping.mapValue(s); ok {
+					metric.AddField(mapping.destKey(key), mapped)
+				}
+			}
+		}
+	}
+	return in
+}
+
+// mapValue returns the value mapped to s, and false if the value is to be
+// left unchanged.
+func (m *mapping) mapValue(s string) (interface{}, bool) {
+	if mapped, ok := m.ValueMappings[s]; ok {
+		return mapped, true
+	}
+	if m.Default != nil {
+		return m.Default, true
+	}
+	return nil, false
+}
+
+// destKey returns the key the value of key is mapped to.
+func (m *mapping) destKey(key string) string {
+	if m.Dest != "" {
+		return m.Dest
+	}
+	return key
+}
+
+func init() {
+	processors.Add("enum", func() telegraf.Processor {
+		return &EnumMapper{}
+	})
+}
@@ -0,0 +1,128 @@
+package enum
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var m1, _ = metric.New("haproxy",
+	map[string]string{"mode": "server", "proxy": "web"},
+	map[string]interface{}{
+		"status":     "UP",
+		"check_code": "L4OK",
+		"weight":     int64(1),
+	},
+	time.Now(),
+)
+
+func TestFieldMapping(t *testing.T) {
+	e := &EnumMapper{
+		Mappings: []mapping{
+			{
+				Field:         "status",
+				ValueMappings: map[string]interface{}{"UP": int64(1), "DOWN": int64(0)},
+			},
+		},
+	}
+	require.NoError(t, e.Init(nil))
+
+	results := e.Apply(m1.Copy())
+	require.Len(t, results, 1)
+	assert.Equal(t, int64(1), results[0].Fields()["status"])
+	assert.Equal(t, "L4OK", results[0].Fields()["check_code"])
+}
+
+func TestFieldMappingDest(t *testing.T) {
+	e := &EnumMapper{
+		Mappings: []mapping{
+			{
+				Field:         "status",
+				Dest:          "status_code",
+				ValueMappings: map[string]interface{}{"UP": int64(1)},
+			},
+		},
+	}
+	require.NoError(t, e.Init(nil))
+
+	results := e.Apply(m1.Copy())
+	require.Len(t, results, 1)
+	assert.Equal(t, "UP", results[0].Fields()["status"])
+	assert.Equal(t, int64(1), results[0].Fields()["status_code"])
+}
+
+func TestFieldMappingDefault(t *testing.T) {
+	e := &EnumMapper{
+		Mappings: []mapping{
+			{
+				Field:         "check_*",
+				Default:       int64(-1),
+				ValueMappings: map[string]interface{}{"L7OK": int64(1)},
+			},
+		},
+	}
+	require.NoError(t, e.Init(nil))
+
+	results := e.Apply(m1.Copy())
+	require.Len(t, results, 1)
+	assert.Equal(t, int64(-1), results[0].Fields()["check_code"])
+}
+
+func TestFieldMappingUnmatched(t *testing.T) {
+	e := &EnumMapper{
+		Mappings: []mapping{
+			{
+				Field:         "status",
+				ValueMappings: map[string]interface{}{"DOWN": int64(0)},
+			},
+			// only string values are mapped
+			{
+				Field:         "weight",
+				Default:       int64(0),
+				ValueMappings: map[string]interface{}{"1": int64(100)},
+			},
+		},
+	}
+	require.NoError(t, e.Init(nil))
+
+	results := e.Apply(m1.Copy())
+	require.Len(t, results, 1)
+	assert.Equal(t, "UP", results[0].Fields()["status"])
+	assert.Equal(t, int64(1), results[0].Fields()["weight"])
+}
+
+func TestTagMapping(t *testing.T) {
+	e := &EnumMapper{
+		Mappings: []mapping{
+			{
+				Tag:           "mode",
+				ValueMappings: map[string]interface{}{"client": int64(3), "server": int64(4)},
+			},
+		},
+	}
+	require.NoError(t, e.Init(nil))
+
+	results := e.Apply(m1.Copy())
+	require.Len(t, results, 1)
+	assert.Equal(t, map[string]string{"mode": "4", "proxy": "web"}, results[0].Tags())
+}
+
+func TestInvalidMapping(t *testing.T) {
+	e := &EnumMapper{
+		Mappings: []mapping{
+			{ValueMappings: map[string]interface{}{"UP": int64(1)}},
+		},
+	}
+	assert.Error(t, e.Init(nil))
+
+	e = &EnumMapper{
+		Mappings: []mapping{
+			{Tag: "mode[", ValueMappings: map[string]interface{}{"UP": int64(1)}},
+		},
+	}
+	assert.Error(t, e.Init(nil))
+}
@@ -0,0 +1,355 @@
+package lua
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yuin/gopher-lua"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+// Default maximum time a call of the script may take.
+const defaultTimeout = 100 * time.Millisecond
+
+type Lua struct {
+	Source  string
+	Script  string
+	Timeout internal.Duration
+
+	Log telegraf.Logger `toml:"-"`
+
+	mu    sync.Mutex
+	state *lua.LState
+	apply lua.LValue
+}
+
+var sampleConfig = `
+  ## Source of the Lua script, defining an apply function called with each
+  ## metric. It returns the metric, nil to drop it, or a list of metrics.
+  source = '''
+function apply(metric)
+  return metric
+end
+'''
+
+  ## Path to a file containing the script, instead of source.
+  # script = "/etc/telegraf/processor.lua"
+
+  ## Maximum time a call of the script may take. The metric is passed on
+  ## unchanged when the script fails or times out.
+  # timeout = "100ms"
+`
+
+func (l *Lua) SampleConfig() string {
+	return sampleConfig
+}
+
+func (l *Lua) Description() string {
+	return "Process metrics using a Lua script"
+}
+
+// Init runs the script in a new sandboxed state, and looks up its apply
+// function.
+func (l *Lua) Init(tags map[string]string) error {
+	if l.Timeout.Duration == 0 {
+		l.Timeout.Duration = defaultTimeout
+	}
+
+	source := l.Source
+	if l.Script != "" {
+		b, err := ioutil.ReadFile(l.Script)
+		if err != nil {
+			return fmt.Errorf("unable to read script: %s", err)
+		}
+		source = string(b)
+	}
+
+	l.state = l.newState()
+	ctx, cancel := context.WithTimeout(context.Background(), l.Timeout.Duration)
+	defer cancel()
+	l.state.SetContext(ctx)
+	defer l.state.RemoveContext()
+	if err := l.state.DoString(source); err != nil {
+		return fmt.Errorf("unable to load script: %s", err)
+	}
+
+	apply, ok := l.state.GetGlobal("apply").(*lua.LFunction)
+	if !ok {
+		return fmt.Errorf("script does not define an apply function")
+	}
+	l.apply = apply
+	return nil
+}
+
+func (l *Lua) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	// the state of the script is not safe for concurrent use, and is kept
+	// between the calls.
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		metrics, err := l.call(m)
+		if err != nil {
+			l.Log.Errorf("Error processing %s: %s", m.Name(), err)
+			out = append(out, m)
+			continue
+		}
+		out = append(out, metrics...)
+	}
+	return out
+}
+
+// newState returns a Lua state with only the libraries that cannot access
+// the system: the base library without the functions loading files or
+// modules, table, string and math. print writes to the log.
+func (l *Lua) newState() *lua.LState {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range []string{"dofile", "loadfile", "module", "require", "_printregs"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+
+	L.SetGlobal("print", L.NewFunction(func(L *lua.LState) int {
+		args := make([]string, L.GetTop())
+		for i := range args {
+			args[i] = L.ToStringMeta(L.Get(i + 1)).String()
+		}
+		l.Log.Info(strings.Join(args, "\t"))
+		return 0
+	}))
+	L.SetGlobal("integer", L.NewFunction(func(L *lua.LState) int {
+		n := float64(L.CheckNumber(1))
+		if math.IsNaN(n) || n >= math.MaxInt64 || n < math.MinInt64 {
+			L.ArgError(1, "number overflows an integer")
+		}
+		ud := L.NewUserData()
+		ud.Value = int64(n)
+		L.Push(ud)
+		return 1
+	}))
+	return L
+}
+
+// call calls apply with the metric, within the time budget.
+func (l *Lua) call(m telegraf.Metric) ([]telegraf.Metric, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), l.Timeout.Duration)
+	defer cancel()
+	l.state.SetContext(ctx)
+	defer l.state.RemoveContext()
+
+	table := toTable(l.state, m)
+	err := l.state.CallByParam(lua.P{
+		Fn:      l.apply,
+		NRet:    1,
+		Protect: true,
+	}, table)
+	if err != nil {
+		return nil, err
+	}
+	ret := l.state.Get(-1)
+	l.state.Pop(1)
+
+	switch ret := ret.(type) {
+	case *lua.LNilType:
+		return nil, nil
+	case *lua.LTable:
+		// a metric has fields, a list of metrics does not.
+		if _, ok := ret.RawGetString("fields").(*lua.LTable); ok {
+			result, err := fromTable(ret, m, table)
+			if err != nil {
+				return nil, err
+			}
+			return []telegraf.Metric{result}, nil
+		}
+
+		var metrics []telegraf.Metric
+		for i := 1; i <= ret.Len(); i++ {
+			t, ok := ret.RawGetInt(i).(*lua.LTable)
+			if !ok {
+				return nil, fmt.Errorf("element %d of the list is not a metric", i)
+			}
+			result, err := fromTable(t, m, table)
+			if err != nil {
+				return nil, err
+			}
+			// the metric passed to apply is only updated once.
+			if t == table {
+				table = nil
+			}
+			metrics = append(metrics, result)
+		}
+		return metrics, nil
+	}
+	return nil, fmt.Errorf("apply returned a %s instead of a metric", ret.Type())
+}
+
+// toTable returns the metric as a table with its name, tags, fields and time
+// in nanoseconds.
+func toTable(L *lua.LState, m telegraf.Metric) *lua.LTable {
+	tags := L.NewTable()
+	for k, v := range m.Tags() {
+		tags.RawSetString(k, lua.LString(v))
+	}
+
+	fields := L.NewTable()
+	for k, v := range m.Fields() {
+		switch v := v.(type) {
+		case float64:
+			fields.RawSetString(k, lua.LNumber(v))
+		case int64:
+			fields.RawSetString(k, lua.LNumber(v))
+		case string:
+			fields.RawSetString(k, lua.LString(v))
+		case bool:
+			fields.RawSetString(k, lua.LBool(v))
+		}
+	}
+
+	t := L.NewTable()
+	t.RawSetString("name", lua.LString(m.Name()))
+	t.RawSetString("tags", tags)
+	t.RawSetString("fields", fields)
+	t.RawSetString("time", lua.LNumber(m.UnixNano()))
+	return t
+}
+
+// fromTable returns the metric described by t. When t is input, the table
+// of the metric m passed to apply, m is updated in place, otherwise a new
+// metric is created with the time of m by default. New metrics are tracked
+// with m, so that m is only delivered once they are.
+func fromTable(t *lua.LTable, m telegraf.Metric, input *lua.LTable) (telegraf.Metric, error) {
+	name, ok := t.RawGetString("name").(lua.LString)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("metric has no name")
+	}
+
+	tags := make(map[string]string)
+	if tt, ok := t.RawGetString("tags").(*lua.LTable); ok {
+		var err error
+		tt.ForEach(func(k, v lua.LValue) {
+			switch v := v.(type) {
+			case lua.LString, lua.LNumber, lua.LBool:
+				tags[k.String()] = v.String()
+			default:
+				err = fmt.Errorf("tag %q has unsupported type %s", k, v.Type())
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	original := m.Fields()
+	fields := make(map[string]interface{})
+	if ft, ok := t.RawGetString("fields").(*lua.LTable); ok {
+		var err error
+		ft.ForEach(func(k, v lua.LValue) {
+			key := k.String()
+			switch v := v.(type) {
+			case lua.LNumber:
+				// Lua numbers are floats, whole numbers of fields that
+				// were integers stay integers. An unchanged integer keeps
+				// its value, which the float may not represent exactly.
+				f := float64(v)
+				old, wasInt := original[key].(int64)
+				if t == input && wasInt && v == lua.LNumber(old) {
+					fields[key] = old
+				} else if t == input && wasInt && f == math.Trunc(f) &&
+					f < math.MaxInt64 && f >= math.MinInt64 {
+					fields[key] = int64(f)
+				} else {
+					fields[key] = f
+				}
+			case lua.LString:
+				fields[key] = string(v)
+			case lua.LBool:
+				fields[key] = bool(v)
+			case *lua.LUserData:
+				if i, ok := v.Value.(int64); ok {
+					fields[key] = i
+					return
+				}
+				err = fmt.Errorf("field %q has unsupported type %s", key, v.Type())
+			default:
+				err = fmt.Errorf("field %q has unsupported type %s", key, v.Type())
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("metric %s has no fields", name)
+	}
+
+	tm := m.Time()
+	if n, ok := t.RawGetString("time").(lua.LNumber); ok {
+		if t != input || n != lua.LNumber(m.UnixNano()) {
+			tm = time.Unix(0, int64(n))
+		}
+	}
+
+	if t != input || !tm.Equal(m.Time()) {
+		result, err := metric.New(string(name), tags, fields, tm, m.Type())
+		if err != nil {
+			return nil, err
+		}
+		return metric.WithTrackingOf(result, m), nil
+	}
+
+	if string(name) != m.Name() {
+		m.SetName(string(name))
+	}
+	current := m.Tags()
+	for k := range current {
+		if _, ok := tags[k]; !ok {
+			m.RemoveTag(k)
+		}
+	}
+	for k, v := range tags {
+		if old, ok := current[k]; !ok || old != v {
+			m.AddTag(k, v)
+		}
+	}
+	// the fields are added first, the last field of a metric cannot be
+	// removed.
+	for k, v := range fields {
+		if old, ok := original[k]; !ok || old != v {
+			m.AddField(k, v)
+		}
+	}
+	for k := range original {
+		if _, ok := fields[k]; !ok {
+			m.RemoveField(k)
+		}
+	}
+	return m, nil
+}
+
+func init() {
+	processors.Add("lua", func() telegraf.Processor {
+		return &Lua{}
+	})
+}
@@ -0,0 +1,381 @@
+package lua
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var m1, _ = metric.New("cpu",
+	map[string]string{"host": "localhost", "cpu": "cpu0"},
+	map[string]interface{}{
+		"usage_idle": 90.5,
+		"count":      int64(3),
+		"state":      "ok",
+	},
+	time.Unix(1500000030, 0),
+)
+
+func assertUnchanged(t *testing.T, m telegraf.Metric) {
+	assert.Equal(t, m1.Name(), m.Name())
+	assert.Equal(t, m1.Tags(), m.Tags())
+	assert.Equal(t, m1.Fields(), m.Fields())
+	assert.Equal(t, m1.Time(), m.Time())
+}
+
+func TestPassThrough(t *testing.T) {
+	l := &Lua{
+		Source: `
+function apply(metric)
+  return metric
+end
+`,
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, l.Init(nil))
+	m := m1.Copy()
+	expected := m.String()
+	results := l.Apply(m)
+	require.Len(t, results, 1)
+	// the metric is left untouched
+	assert.True(t, m == results[0])
+	assert.Equal(t, expected, results[0].String())
+}
+
+func TestMutate(t *testing.T) {
+	l := &Lua{
+		Source: `
+function apply(metric)
+  metric.name = "processor"
+  metric.tags.cpu = nil
+  metric.tags.region = "eu"
+  metric.fields.usage_busy = 100 - metric.fields.usage_idle
+  metric.fields.usage_idle = nil
+  metric.fields.count = metric.fields.count * 2
+  metric.fields.state = string.upper(metric.fields.state)
+  return metric
+end
+`,
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, l.Init(nil))
+	m := m1.Copy()
+	results := l.Apply(m)
+	require.Len(t, results, 1)
+	// the metric is updated in place
+	assert.True(t, m == results[0])
+	assert.Equal(t, "processor", m.Name())
+	assert.Equal(t, map[string]string{"host": "localhost", "region": "eu"}, m.Tags())
+	assert.Equal(t, map[string]interface{}{
+		"usage_busy": 9.5,
+		"count":      int64(6),
+		"state":      "OK",
+	}, m.Fields())
+	assert.Equal(t, time.Unix(1500000030, 0), m.Time())
+}
+
+func TestIntegerFields(t *testing.T) {
+	l := &Lua{
+		Source: `
+function apply(metric)
+  metric.fields.count = metric.fields.count / 2
+  metric.fields.total = integer(10)
+  metric.fields.ratio = 10
+  return metric
+end
+`,
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, l.Init(nil))
+	results := l.Apply(m1.Copy())
+	require.Len(t, results, 1)
+	fields := results[0].Fields()
+	// integers stay integers only while their value is whole
+	assert.Equal(t, 1.5, fields["count"])
+	assert.Equal(t, int64(10), fields["total"])
+	assert.Equal(t, 10.0, fields["ratio"])
+}
+
+func TestLargeIntegerFields(t *testing.T) {
+	l := &Lua{
+		Source: `
+function apply(metric)
+  metric.fields.count = metric.fields.count + 1
+  return metric
+end
+`,
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, l.Init(nil))
+	// neither the field nor the time are exact as floats
+	m, err := metric.New("counter",
+		map[string]string{},
+		map[string]interface{}{
+			"bytes": int64(1<<53 + 1),
+			"count": int64(3),
+		},
+		time.Unix(1500000030, 1),
+	)
+	require.NoError(t, err)
+	results := l.Apply(m)
+	require.Len(t, results, 1)
+	assert.Equal(t, map[string]interface{}{
+		"bytes": int64(1<<53 + 1),
+		"count": int64(4),
+	}, results[0].Fields())
+	assert.Equal(t, time.Unix(1500000030, 1), results[0].Time())
+}
+
+func TestDrop(t *testing.T) {
+	l := &Lua{
+		Source: `
+function apply(metric)
+  if metric.tags.host == "localhost" then
+    return nil
+  end
+  return metric
+end
+`,
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, l.Init(nil))
+	assert.Len(t, l.Apply(m1.Copy()), 0)
+}
+
+func TestEmit(t *testing.T) {
+	l := &Lua{
+		Source: `
+function apply(metric)
+  local status = {
+    name = "cpu_status",
+    tags = {host = metric.tags.host},
+    fields = {state = metric.fields.state},
+  }
+  return {metric, status}
+end
+`,
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, l.Init(nil))
+	m := m1.Copy()
+	results := l.Apply(m)
+	require.Len(t, results, 2)
+	assert.True(t, m == results[0])
+	assert.Equal(t, "cpu_status", results[1].Name())
+	assert.Equal(t, map[string]string{"host": "localhost"}, results[1].Tags())
+	assert.Equal(t, map[string]interface{}{"state": "ok"}, results[1].Fields())
+	// the time of the input by default
+	assert.Equal(t, m.Time(), results[1].Time())
+}
+
+func TestTime(t *testing.T) {
+	l := &Lua{
+		Source: `
+function apply(metric)
+  metric.time = metric.time - metric.time % 60e9
+  return metric
+end
+`,
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, l.Init(nil))
+	results := l.Apply(m1.Copy())
+	require.Len(t, results, 1)
+	assert.Equal(t, time.Unix(1500000000, 0), results[0].Time())
+}
+
+func TestTimeTracking(t *testing.T) {
+	l := &Lua{
+		Source: `
+function apply(metric)
+  metric.time = metric.time - metric.time % 60e9
+  return metric
+end
+`,
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, l.Init(nil))
+	rp := models.NewRunningProcessor("lua", l, &models.ProcessorConfig{Name: "lua"})
+
+	var infos []telegraf.DeliveryInfo
+	tracked, _ := metric.WithGroupTracking([]telegraf.Metric{m1.Copy()},
+		func(info telegraf.DeliveryInfo) {
+			infos = append(infos, info)
+		})
+	results := rp.Apply(tracked...)
+	require.Len(t, results, 1)
+	assert.Equal(t, time.Unix(1500000000, 0), results[0].Time())
+
+	// the input is only delivered once the retimed metric is written
+	assert.Len(t, infos, 0)
+	results[0].Accept()
+	require.Len(t, infos, 1)
+	assert.True(t, infos[0].Delivered())
+}
+
+func TestGlobalsPersist(t *testing.T) {
+	l := &Lua{
+		Source: `
+count = 0
+function apply(metric)
+  count = count + 1
+  metric.fields.seen = integer(count)
+  return metric
+end
+`,
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, l.Init(nil))
+	results := l.Apply(m1.Copy(), m1.Copy())
+	require.Len(t, results, 2)
+	assert.Equal(t, int64(1), results[0].Fields()["seen"])
+	assert.Equal(t, int64(2), results[1].Fields()["seen"])
+}
+
+func TestScriptFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "lua")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`
+function apply(metric)
+  metric.tags.script = "file"
+  return metric
+end
+`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	l := &Lua{Script: f.Name(), Log: testutil.Logger{}}
+	require.NoError(t, l.Init(nil))
+	results := l.Apply(m1.Copy())
+	require.Len(t, results, 1)
+	assert.Equal(t, "file", results[0].Tags()["script"])
+}
+
+func TestErrorPassesMetric(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{
+			name: "runtime error",
+			source: `
+function apply(metric)
+  return metric.fields.missing + 1
+end
+`,
+		},
+		{
+			name: "invalid result",
+			source: `
+function apply(metric)
+  return "metric"
+end
+`,
+		},
+		{
+			name: "unsupported field",
+			source: `
+function apply(metric)
+  metric.fields.list = {1, 2}
+  return metric
+end
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &Lua{Source: tt.source, Log: testutil.Logger{}}
+			require.NoError(t, l.Init(nil))
+			results := l.Apply(m1.Copy())
+			require.Len(t, results, 1)
+			assertUnchanged(t, results[0])
+		})
+	}
+}
+
+func TestInitError(t *testing.T) {
+	tests := []struct {
+		name string
+		lua  *Lua
+	}{
+		{
+			name: "syntax error",
+			lua:  &Lua{Source: `function apply(metric`},
+		},
+		{
+			name: "no apply function",
+			lua:  &Lua{Source: `x = 1`},
+		},
+		{
+			name: "missing script",
+			lua:  &Lua{Script: "./testdata/does_not_exist.lua"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.lua.Log = testutil.Logger{}
+			assert.Error(t, tt.lua.Init(nil))
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	l := &Lua{
+		Source: `
+function apply(metric)
+  if metric.tags.cpu == "cpu0" then
+    while true do end
+  end
+  metric.tags.done = "yes"
+  return metric
+end
+`,
+		Timeout: internal.Duration{Duration: 50 * time.Millisecond},
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, l.Init(nil))
+
+	start := time.Now()
+	results := l.Apply(m1.Copy())
+	require.Len(t, results, 1)
+	assertUnchanged(t, results[0])
+	assert.True(t, time.Since(start) < 5*time.Second)
+
+	// the script still runs after a timeout
+	m := m1.Copy()
+	m.RemoveTag("cpu")
+	results = l.Apply(m)
+	require.Len(t, results, 1)
+	assert.Equal(t, "yes", results[0].Tags()["done"])
+}
+
+func TestSandbox(t *testing.T) {
+	for _, name := range []string{"io", "os", "require", "dofile", "loadfile", "debug"} {
+		t.Run(name, func(t *testing.T) {
+			l := &Lua{
+				Source: `
+function apply(metric)
+  metric.tags.available = tostring(` + name + ` ~= nil)
+  return metric
+end
+`,
+				Log: testutil.Logger{},
+			}
+			require.NoError(t, l.Init(nil))
+			results := l.Apply(m1.Copy())
+			require.Len(t, results, 1)
+			assert.Equal(t, "false", results[0].Tags()["available"])
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package rate
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// Default time after which the state of a series that was not seen again is
+// forgotten.
+const defaultTTL = 10 * time.Minute
+
+// Number of shards of the series state. The series are spread between the
+// shards by HashID, each shard has its own lock so that the workers of the
+// processor handling different series do not wait for each other.
+const numShards = 16
+
+// Modes of the processor.
+const (
+	modeRate  = "rate"
+	modeDelta = "delta"
+)
+
+type Rate struct {
+	Fields       []string
+	Mode         string
+	Suffix       string
+	DropOriginal bool
+	TTL          internal.Duration `toml:"ttl"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	filter        filter.Filter
+	counterResets selfstat.Stat
+	shards        [numShards]shard
+
+	// now is replaced in tests.
+	now func() time.Time
+}
+
+// shard is the state of a part of the series.
+type shard struct {
+	mu sync.Mutex
+	// series is the state of the series by their HashID.
+	series map[uint64]*series
+	// nextSweep is the earliest time the expired series are removed.
+	nextSweep time.Time
+}
+
+// series is the state of a series.
+type series struct {
+	// seen is the last time the series was seen.
+	seen time.Time
+	// samples are the last values of the counters by field.
+	samples map[string]sample
+}
+
+// sample is a value of a counter, an int64 or a float64.
+type sample struct {
+	value interface{}
+	time  time.Time
+}
+
+var sampleConfig = `
+  ## Counter fields to compute the rate or delta of, matched by name or by
+  ## glob pattern. All the numeric fields by default.
+  # fields = ["bytes_*", "packets_*"]
+
+  ## Either "rate", the change per second, or "delta", the change since the
+  ## previous value.
+  # mode = "rate"
+
+  ## Suffix of the fields the results are written to, "_rate" or "_delta" by
+  ## default.
+  # suffix = "_rate"
+
+  ## Remove the counter fields from the metrics. The metrics are dropped when
+  ## no result could be computed for them.
+  # drop_original = false
+
+  ## Time after which the state of a series that was not seen again is
+  ## forgotten.
+  # ttl = "10m"
+`
+
+func (r *Rate) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Rate) Description() string {
+	return "Compute the rate or delta of counter fields"
+}
+
+func (r *Rate) Init(tags map[string]string) error {
+	r.counterResets = selfstat.Register("rate", "counter_resets", tags)
+
+	switch r.Mode {
+	case "":
+		r.Mode = modeRate
+	case modeRate, modeDelta:
+	default:
+		return fmt.Errorf("invalid mode %q, must be %q or %q",
+			r.Mode, modeRate, modeDelta)
+	}
+	if r.Suffix == "" {
+		r.Suffix = "_" + r.Mode
+	}
+	if r.TTL.Duration == 0 {
+		r.TTL.Duration = defaultTTL
+	}
+
+	var err error
+	r.filter, err = filter.Compile(r.Fields)
+	if err != nil {
+		return fmt.Errorf("invalid fields: %s", err)
+	}
+
+	for i := range r.shards {
+		r.shards[i].series = make(map[uint64]*series)
+	}
+	if r.now == nil {
+		r.now = time.Now
+	}
+	return nil
+}
+
+func (r *Rate) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	now := r.now()
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, metric := range in {
+		id := metric.HashID()
+		sh := &r.shards[id%numShards]
+
+		sh.mu.Lock()
+		r.sweep(sh, now)
+		keep := r.process(sh, id, metric, now)
+		sh.mu.Unlock()
+
+		if keep {
+			out = append(out, metric)
+		}
+	}
+	return out
+}
+
+// process records the counters of the metric in the series id of the locked
+// shard, and adds their results. It returns false if the metric must be
+// dropped.
+func (r *Rate) process(sh *shard, id uint64, metric telegraf.Metric, now time.Time) bool {
+	s, ok := sh.series[id]
+	if !ok {
+		s = &series{samples: make(map[string]sample)}
+		sh.series[id] = s
+	}
+	s.seen = now
+
+	var counters []string
+	results := make(map[string]interface{})
+	for key, value := range metric.Fields() {
+		if r.filter != nil && !r.filter.Match(key) {
+			continue
+		}
+		switch value.(type) {
+		case int64, float64:
+		default:
+			continue
+		}
+		counters = append(counters, key)
+
+		current := sample{value: value, time: metric.Time()}
+		previous, ok := s.samples[key]
+		if ok && !current.time.After(previous.time) {
+			// out of order, the newer value is kept.
+			continue
+		}
+		s.samples[key] = current
+		if !ok {
+			continue
+		}
+
+		result, ok := r.compute(previous, current)
+		if !ok {
+			r.counterResets.Incr(1)
+			r.Log.Debugf("Counter %s of %s was reset", key, metric.Name())
+			continue
+		}
+		results[key+r.Suffix] = result
+	}
+
+	for key, value := range results {
+		metric.AddField(key, value)
+	}
+	if r.DropOriginal && len(counters) > 0 {
+		if len(results) == 0 && len(counters) == len(metric.Fields()) {
+			// only counters without results are left.
+			return false
+		}
+		for _, key := range counters {
+			metric.RemoveField(key)
+		}
+	}
+	return true
+}
+
+// compute returns the result of the counter between the previous and the
+// current sample, and false if the counter was reset.
+func (r *Rate) compute(previous, current sample) (interface{}, bool) {
+	var delta interface{}
+	switch v := current.value.(type) {
+	case int64:
+		p, ok := previous.value.(int64)
+		if !ok {
+			delta = float64(v) - toFloat(previous.value)
+			break
+		}
+		if v < p {
+			return nil, false
+		}
+		delta = v - p
+	case float64:
+		delta = v - toFloat(previous.value)
+	}
+	if f, ok := delta.(float64); ok && f < 0 {
+		return nil, false
+	}
+
+	if r.Mode == modeDelta {
+		return delta, true
+	}
+	seconds := current.time.Sub(previous.time).Seconds()
+	return toFloat(delta) / seconds, true
+}
+
+func toFloat(v interface{}) float64 {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+// sweep forgets the series of the shard not seen within the TTL. The series
+// expire one by one, they are only swept every tenth of the TTL.
+func (r *Rate) sweep(sh *shard, now time.Time) {
+	if now.Before(sh.nextSweep) {
+		return
+	}
+	for id, s := range sh.series {
+		if now.Sub(s.seen) >= r.TTL.Duration {
+			delete(sh.series, id)
+		}
+	}
+	sh.nextSweep = now.Add(r.TTL.Duration / 10)
+}
+
+func init() {
+	processors.Add("rate", func() telegraf.Processor {
+		return &Rate{}
+	})
+}
@@ -0,0 +1,216 @@
+package rate
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Unix(1500000000, 0)
+
+// netMetric returns a metric of the series of host, seconds after start.
+func netMetric(host string, seconds int, fields map[string]interface{}) telegraf.Metric {
+	m, _ := metric.New("net",
+		map[string]string{"host": host},
+		fields,
+		start.Add(time.Duration(seconds)*time.Second),
+	)
+	return m
+}
+
+func apply(t *testing.T, r *Rate, m telegraf.Metric) map[string]interface{} {
+	results := r.Apply(m)
+	require.Len(t, results, 1)
+	return results[0].Fields()
+}
+
+// seriesCount returns the number of series the processor keeps the state of.
+func seriesCount(r *Rate) int {
+	n := 0
+	for i := range r.shards {
+		n += len(r.shards[i].series)
+	}
+	return n
+}
+
+func TestRate(t *testing.T) {
+	r := &Rate{Log: testutil.Logger{}}
+	require.NoError(t, r.Init(nil))
+
+	assert.Equal(t, map[string]interface{}{
+		"bytes_recv": int64(1000),
+		"load":       0.5,
+		"state":      "up",
+	}, apply(t, r, netMetric("a", 0, map[string]interface{}{
+		"bytes_recv": int64(1000),
+		"load":       0.5,
+		"state":      "up",
+	})))
+
+	assert.Equal(t, map[string]interface{}{
+		"bytes_recv":      int64(3000),
+		"bytes_recv_rate": 200.0,
+		"load":            1.5,
+		"load_rate":       0.1,
+		"state":           "up",
+	}, apply(t, r, netMetric("a", 10, map[string]interface{}{
+		"bytes_recv": int64(3000),
+		"load":       1.5,
+		"state":      "up",
+	})))
+}
+
+func TestDelta(t *testing.T) {
+	r := &Rate{
+		Fields: []string{"bytes_*"},
+		Mode:   "delta",
+		Suffix: "_diff",
+		Log:    testutil.Logger{},
+	}
+	require.NoError(t, r.Init(nil))
+
+	apply(t, r, netMetric("a", 0, map[string]interface{}{
+		"bytes_recv": int64(1000),
+		"bytes_sent": 10.0,
+		"packets":    int64(1),
+	}))
+	assert.Equal(t, map[string]interface{}{
+		"bytes_recv":      int64(1500),
+		"bytes_recv_diff": int64(500),
+		"bytes_sent":      12.5,
+		"bytes_sent_diff": 2.5,
+		"packets":         int64(2),
+	}, apply(t, r, netMetric("a", 10, map[string]interface{}{
+		"bytes_recv": int64(1500),
+		"bytes_sent": 12.5,
+		"packets":    int64(2),
+	})))
+}
+
+func TestSeries(t *testing.T) {
+	r := &Rate{Mode: "delta", Log: testutil.Logger{}}
+	require.NoError(t, r.Init(nil))
+
+	apply(t, r, netMetric("a", 0, map[string]interface{}{"bytes": int64(100)}))
+	apply(t, r, netMetric("b", 0, map[string]interface{}{"bytes": int64(1000)}))
+	assert.Equal(t, int64(5), apply(t, r, netMetric("a", 10,
+		map[string]interface{}{"bytes": int64(105)}))["bytes_delta"])
+	assert.Equal(t, int64(50), apply(t, r, netMetric("b", 10,
+		map[string]interface{}{"bytes": int64(1050)}))["bytes_delta"])
+	assert.Equal(t, 2, seriesCount(r))
+}
+
+func TestCounterReset(t *testing.T) {
+	r := &Rate{Log: testutil.Logger{}}
+	require.NoError(t, r.Init(map[string]string{"processor": "rate"}))
+	assert.Equal(t, map[string]string{"processor": "rate"}, r.counterResets.Tags())
+
+	apply(t, r, netMetric("a", 0, map[string]interface{}{"bytes": int64(1000)}))
+	resets := r.counterResets.Get()
+
+	fields := apply(t, r, netMetric("a", 10, map[string]interface{}{"bytes": int64(10)}))
+	assert.NotContains(t, fields, "bytes_rate")
+	assert.Equal(t, resets+1, r.counterResets.Get())
+
+	// the rate is computed again from the value after the reset
+	fields = apply(t, r, netMetric("a", 20, map[string]interface{}{"bytes": int64(110)}))
+	assert.Equal(t, 10.0, fields["bytes_rate"])
+}
+
+func TestOutOfOrder(t *testing.T) {
+	r := &Rate{Log: testutil.Logger{}}
+	require.NoError(t, r.Init(nil))
+
+	apply(t, r, netMetric("a", 10, map[string]interface{}{"bytes": int64(1000)}))
+	fields := apply(t, r, netMetric("a", 0, map[string]interface{}{"bytes": int64(500)}))
+	assert.NotContains(t, fields, "bytes_rate")
+	fields = apply(t, r, netMetric("a", 10, map[string]interface{}{"bytes": int64(1000)}))
+	assert.NotContains(t, fields, "bytes_rate")
+
+	fields = apply(t, r, netMetric("a", 20, map[string]interface{}{"bytes": int64(1100)}))
+	assert.Equal(t, 10.0, fields["bytes_rate"])
+}
+
+func TestDropOriginal(t *testing.T) {
+	r := &Rate{
+		Fields:       []string{"bytes"},
+		DropOriginal: true,
+		Log:          testutil.Logger{},
+	}
+	require.NoError(t, r.Init(nil))
+
+	// only counters without results
+	assert.Len(t, r.Apply(netMetric("a", 0,
+		map[string]interface{}{"bytes": int64(1000)})), 0)
+	assert.Equal(t, map[string]interface{}{"bytes_rate": 100.0}, apply(t, r,
+		netMetric("a", 10, map[string]interface{}{"bytes": int64(2000)})))
+
+	// other fields are kept
+	assert.Equal(t, map[string]interface{}{"state": "up"}, apply(t, r,
+		netMetric("b", 0, map[string]interface{}{
+			"bytes": int64(1000),
+			"state": "up",
+		})))
+}
+
+func TestTTL(t *testing.T) {
+	now := start
+	r := &Rate{
+		TTL: internal.Duration{Duration: time.Minute},
+		Log: testutil.Logger{},
+		now: func() time.Time { return now },
+	}
+	require.NoError(t, r.Init(nil))
+
+	apply(t, r, netMetric("a", 0, map[string]interface{}{"bytes": int64(1000)}))
+	apply(t, r, netMetric("b", 0, map[string]interface{}{"bytes": int64(1000)}))
+
+	now = now.Add(50 * time.Second)
+	apply(t, r, netMetric("a", 50, map[string]interface{}{"bytes": int64(1500)}))
+	assert.Equal(t, 2, seriesCount(r))
+
+	// b expired, its next value is the first one again
+	now = now.Add(20 * time.Second)
+	fields := apply(t, r, netMetric("b", 70, map[string]interface{}{"bytes": int64(1700)}))
+	assert.NotContains(t, fields, "bytes_rate")
+	fields = apply(t, r, netMetric("a", 70, map[string]interface{}{"bytes": int64(1700)}))
+	assert.Equal(t, 10.0, fields["bytes_rate"])
+}
+
+func TestConcurrentApply(t *testing.T) {
+	r := &Rate{Mode: "delta", Log: testutil.Logger{}}
+	require.NoError(t, r.Init(nil))
+
+	var wg sync.WaitGroup
+	for _, host := range []string{"a", "b", "c", "d"} {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				results := r.Apply(netMetric(host, j,
+					map[string]interface{}{"bytes": int64(j)}))
+				if j > 0 && assert.Len(t, results, 1) {
+					assert.Equal(t, int64(1), results[0].Fields()["bytes_delta"])
+				}
+			}
+		}(host)
+	}
+	wg.Wait()
+	assert.Equal(t, 4, seriesCount(r))
+}
+
+func TestInvalidConfig(t *testing.T) {
+	r := &Rate{Fields: []string{"bytes_["}, Log: testutil.Logger{}}
+	assert.Error(t, r.Init(nil))
+
+	r = &Rate{Mode: "average", Log: testutil.Logger{}}
+	assert.Error(t, r.Init(nil))
+}
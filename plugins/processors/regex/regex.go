@@ -0,0 +1,124 @@
+package regex
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+type Regex struct {
+	Tags   []converter
+	Fields []converter
+
+	patterns map[string]*regexp.Regexp
+}
+
+// converter is a replace rule, applied to the value of the tag or field Key.
+type converter struct {
+	Key         string
+	Pattern     string
+	Replacement string
+	ResultKey   string
+}
+
+var sampleConfig = `
+  ## Rules are applied in order, the tag rules before the field rules. A rule
+  ## sees the value written by the previous rules.
+  ##
+  ## Replace the values of the tag "resp_code" such as 200 by 2xx.
+  # [[processors.regex.tags]]
+  #   key = "resp_code"
+  #   pattern = "^(\\d)\\d\\d$"
+  #   replacement = "${1}xx"
+  #
+  ## Strip the query string of the string field "url", and write the result to
+  ## the field "path" instead of replacing the value of "url". The result key
+  ## is only written when the pattern matches.
+  # [[processors.regex.fields]]
+  #   key = "url"
+  #   pattern = "^([^?]*)\\?.*$"
+  #   replacement = "${1}"
+  #   result_key = "path"
+  #
+  ## Collapse the numeric segments of the field "path".
+  # [[processors.regex.fields]]
+  #   key = "path"
+  #   pattern = "/\\d+(/|$)"
+  #   replacement = "/:id${1}"
+`
+
+func (r *Regex) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Regex) Description() string {
+	return "Transforms tag and field values with regex patterns"
+}
+
+func (r *Regex) Init(tags map[string]string) error {
+	r.patterns = make(map[string]*regexp.Regexp)
+	for _, rules := range [][]converter{r.Tags, r.Fields} {
+		for _, c := range rules {
+			if _, ok := r.patterns[c.Pattern]; ok {
+				continue
+			}
+			re, err := regexp.Compile(c.Pattern)
+			if err != nil {
+				return fmt.Errorf("invalid pattern %q of key %q: %s",
+					c.Pattern, c.Key, err)
+			}
+			r.patterns[c.Pattern] = re
+		}
+	}
+	return nil
+}
+
+func (r *Regex) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, metric := range in {
+		for _, c := range r.Tags {
+			if value, ok := metric.Tags()[c.Key]; ok {
+				if result, ok := r.convert(c, value); ok {
+					metric.AddTag(resultKey(c), result)
+				}
+			}
+		}
+
+		for _, c := range r.Fields {
+			if value, ok := metric.Fields()[c.Key]; ok {
+				// only string fields are converted
+				if s, ok := value.(string); ok {
+					if result, ok := r.convert(c, s); ok {
+						metric.AddField(resultKey(c), result)
+					}
+				}
+			}
+		}
+	}
+	return in
+}
+
+// convert returns the value replaced by the rule, and false if the pattern
+// does not match the value.
+func (r *Regex) convert(c converter, value string) (string, bool) {
+	re := r.patterns[c.Pattern]
+	if !re.MatchString(value) {
+		return "", false
+	}
+	return re.ReplaceAllString(value, c.Replacement), true
+}
+
+// resultKey returns the key the result of the rule is written to.
+func resultKey(c converter) string {
+	if c.ResultKey != "" {
+		return c.ResultKey
+	}
+	return c.Key
+}
+
+func init() {
+	processors.Add("regex", func() telegraf.Processor {
+		return &Regex{}
+	})
+}
@@ -0,0 +1,160 @@
+package regex
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var m1, _ = metric.New("http_response",
+	map[string]string{
+		"server":    "https://example.com/api/users/42?token=secret",
+		"resp_code": "200",
+	},
+	map[string]interface{}{
+		"request":       "/api/users/42/orders/7?page=2",
+		"response_time": 0.25,
+	},
+	time.Now(),
+)
+
+func TestTagConversions(t *testing.T) {
+	r := &Regex{
+		Tags: []converter{
+			{
+				Key:         "resp_code",
+				Pattern:     "^(\\d)\\d\\d$",
+				Replacement: "${1}xx",
+			},
+			{
+				Key:         "server",
+				Pattern:     "^([^?]*)\\?.*$",
+				Replacement: "${1}",
+				ResultKey:   "url",
+			},
+		},
+	}
+	require.NoError(t, r.Init(nil))
+
+	processed := r.Apply(m1.Copy())
+	require.Len(t, processed, 1)
+	assert.Equal(t, map[string]string{
+		"server":    "https://example.com/api/users/42?token=secret",
+		"resp_code": "2xx",
+		"url":       "https://example.com/api/users/42",
+	}, processed[0].Tags())
+}
+
+func TestFieldConversions(t *testing.T) {
+	r := &Regex{
+		Fields: []converter{
+			{
+				Key:         "request",
+				Pattern:     "^([^?]*)\\?.*$",
+				Replacement: "${1}",
+				ResultKey:   "path",
+			},
+			// applied to the result of the previous rule
+			{
+				Key:         "path",
+				Pattern:     "/\\d+(/|$)",
+				Replacement: "/:id${1}",
+			},
+			// not a string field
+			{
+				Key:         "response_time",
+				Pattern:     ".*",
+				Replacement: "fast",
+			},
+		},
+	}
+	require.NoError(t, r.Init(nil))
+
+	processed := r.Apply(m1.Copy())
+	require.Len(t, processed, 1)
+	assert.Equal(t, map[string]interface{}{
+		"request":       "/api/users/42/orders/7?page=2",
+		"path":          "/api/users/:id/orders/:id",
+		"response_time": 0.25,
+	}, processed[0].Fields())
+}
+
+func TestNoMatch(t *testing.T) {
+	r := &Regex{
+		Tags: []converter{
+			{
+				Key:         "resp_code",
+				Pattern:     "^5\\d\\d$",
+				Replacement: "error",
+				ResultKey:   "status",
+			},
+			{
+				Key:         "missing",
+				Pattern:     ".*",
+				Replacement: "found",
+			},
+		},
+		Fields: []converter{
+			{
+				Key:         "request",
+				Pattern:     "^/metrics",
+				Replacement: "metrics",
+			},
+		},
+	}
+	require.NoError(t, r.Init(nil))
+
+	processed := r.Apply(m1.Copy())
+	require.Len(t, processed, 1)
+	assert.Equal(t, m1.Tags(), processed[0].Tags())
+	assert.Equal(t, m1.Fields(), processed[0].Fields())
+}
+
+func TestInvalidPattern(t *testing.T) {
+	r := &Regex{
+		Tags: []converter{
+			{
+				Key:         "resp_code",
+				Pattern:     "^2",
+				Replacement: "two",
+			},
+		},
+		Fields: []converter{
+			{
+				Key:         "request",
+				Pattern:     "^(\\d",
+				Replacement: "invalid",
+			},
+		},
+	}
+	assert.Error(t, r.Init(nil))
+}
+
+func TestConcurrentApply(t *testing.T) {
+	r := &Regex{
+		Tags: []converter{
+			{
+				Key:         "resp_code",
+				Pattern:     "^(\\d)\\d\\d$",
+				Replacement: "${1}xx",
+			},
+		},
+	}
+	require.NoError(t, r.Init(nil))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			processed := r.Apply(m1.Copy())
+			assert.Equal(t, "2xx", processed[0].Tags()["resp_code"])
+		}()
+	}
+	wg.Wait()
+}
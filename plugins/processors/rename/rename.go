@@ -0,0 +1,112 @@
+package rename
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+type Rename struct {
+	Replaces []replace `toml:"replace"`
+
+	filters []filter.Filter
+}
+
+// replace renames the measurement, tags or fields matching one of
+// Measurement, Tag or Field to Dest.
+type replace struct {
+	Measurement string
+	Tag         string
+	Field       string
+	Dest        string
+}
+
+var sampleConfig = `
+  ## Replaces are applied in order. Each one renames either the measurement,
+  ## a tag or a field, matched by name or by glob pattern, to dest. When
+  ## several tags or fields match a pattern, only one is kept.
+  # [[processors.rename.replace]]
+  #   measurement = "network_interface_throughput"
+  #   dest = "throughput"
+  #
+  # [[processors.rename.replace]]
+  #   tag = "hostname"
+  #   dest = "host"
+  #
+  # [[processors.rename.replace]]
+  #   field = "lower"
+  #   dest = "min"
+`
+
+func (r *Rename) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Rename) Description() string {
+	return "Rename measurements, tags, and fields that pass through this filter."
+}
+
+func (r *Rename) Init(tags map[string]string) error {
+	r.filters = make([]filter.Filter, len(r.Replaces))
+	for i, replace := range r.Replaces {
+		var pattern string
+		switch {
+		case replace.Measurement != "":
+			pattern = replace.Measurement
+		case replace.Tag != "":
+			pattern = replace.Tag
+		case replace.Field != "":
+			pattern = replace.Field
+		}
+		if pattern == "" || replace.Dest == "" {
+			return fmt.Errorf("replace %d requires dest and one of measurement, tag or field",
+				i+1)
+		}
+		f, err := filter.Compile([]string{pattern})
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q of replace %d: %s",
+				pattern, i+1, err)
+		}
+		r.filters[i] = f
+	}
+	return nil
+}
+
+func (r *Rename) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, metric := range in {
+		for i, replace := range r.Replaces {
+			f := r.filters[i]
+			switch {
+			case replace.Measurement != "":
+				if f.Match(metric.Name()) {
+					metric.SetName(replace.Dest)
+				}
+			case replace.Tag != "":
+				for key, value := range metric.Tags() {
+					if key != replace.Dest && f.Match(key) {
+						metric.RemoveTag(key)
+						metric.AddTag(replace.Dest, value)
+					}
+				}
+			case replace.Field != "":
+				for key, value := range metric.Fields() {
+					if key != replace.Dest && f.Match(key) {
+						// the new field is added first, the last field of a
+						// metric cannot be removed.
+						metric.AddField(replace.Dest, value)
+						metric.RemoveField(key)
+					}
+				}
+			}
+		}
+	}
+	return in
+}
+
+func init() {
+	processors.Add("rename", func() telegraf.Processor {
+		return &Rename{}
+	})
+}
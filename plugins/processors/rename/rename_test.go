@@ -0,0 +1,107 @@
+package rename
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var m1, _ = metric.New("network_interface_throughput",
+	map[string]string{"hostname": "localhost", "iface_name": "eth0", "region": "eu"},
+	map[string]interface{}{"lower": int64(1), "upper": int64(9)},
+	time.Now(),
+)
+var m2, _ = metric.New("disk_io",
+	map[string]string{},
+	map[string]interface{}{"value": 42.0},
+	time.Now(),
+)
+var m3, _ = metric.New("cpu",
+	map[string]string{},
+	map[string]interface{}{"value": 42.0},
+	time.Now(),
+)
+
+func TestMeasurementRename(t *testing.T) {
+	r := &Rename{
+		Replaces: []replace{
+			{Measurement: "network_interface_throughput", Dest: "throughput"},
+			{Measurement: "disk_*", Dest: "disk"},
+		},
+	}
+	require.NoError(t, r.Init(nil))
+
+	results := r.Apply(m1.Copy(), m2.Copy(), m3.Copy())
+	require.Len(t, results, 3)
+	assert.Equal(t, "throughput", results[0].Name())
+	assert.Equal(t, "disk", results[1].Name())
+	assert.Equal(t, "cpu", results[2].Name())
+}
+
+func TestTagRename(t *testing.T) {
+	r := &Rename{
+		Replaces: []replace{
+			{Tag: "hostname", Dest: "host"},
+			{Tag: "iface_*", Dest: "interface"},
+		},
+	}
+	require.NoError(t, r.Init(nil))
+
+	results := r.Apply(m1.Copy())
+	require.Len(t, results, 1)
+	assert.Equal(t, map[string]string{
+		"host":      "localhost",
+		"interface": "eth0",
+		"region":    "eu",
+	}, results[0].Tags())
+}
+
+func TestFieldRename(t *testing.T) {
+	r := &Rename{
+		Replaces: []replace{
+			{Field: "lower", Dest: "min"},
+			{Field: "upper", Dest: "max"},
+		},
+	}
+	require.NoError(t, r.Init(nil))
+
+	results := r.Apply(m1.Copy())
+	require.Len(t, results, 1)
+	assert.Equal(t, map[string]interface{}{
+		"min": int64(1),
+		"max": int64(9),
+	}, results[0].Fields())
+}
+
+func TestSingleFieldRename(t *testing.T) {
+	r := &Rename{
+		Replaces: []replace{
+			{Field: "value", Dest: "gauge"},
+		},
+	}
+	require.NoError(t, r.Init(nil))
+
+	results := r.Apply(m2.Copy())
+	require.Len(t, results, 1)
+	assert.Equal(t, map[string]interface{}{"gauge": 42.0}, results[0].Fields())
+}
+
+func TestInvalidReplace(t *testing.T) {
+	r := &Rename{
+		Replaces: []replace{
+			{Tag: "hostname"},
+		},
+	}
+	assert.Error(t, r.Init(nil))
+
+	r = &Rename{
+		Replaces: []replace{
+			{Tag: "host[", Dest: "host"},
+		},
+	}
+	assert.Error(t, r.Init(nil))
+}
@@ -10,3 +10,13 @@ type Processor interface {
 	// Apply the filter to the given metric
 	Apply(in ...Metric) []Metric
 }
+
+// InitProcessor is a Processor checking its configuration once it is loaded,
+// so that configuration errors are reported at startup.
+type InitProcessor interface {
+	Processor
+
+	// Init prepares the processor, returning an error if its configuration
+	// is invalid. tags identify the processor in the selfstat it registers.
+	Init(tags map[string]string) error
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -135,6 +136,39 @@ func (a *Accumulator) AddError(err error) {
 	a.Unlock()
 }
 
+// WithTracking returns a TrackingAccumulator adding its metrics to a, and
+// reporting them delivered as soon as they are added.
+func (a *Accumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
+	return &TrackingAccumulator{
+		Accumulator: a,
+		delivered:   make(chan telegraf.DeliveryInfo, maxTracked),
+	}
+}
+
+// TrackingAccumulator is the telegraf.TrackingAccumulator of Accumulator.
+type TrackingAccumulator struct {
+	*Accumulator
+	delivered chan telegraf.DeliveryInfo
+}
+
+func (a *TrackingAccumulator) AddTrackingMetricGroup(
+	group []telegraf.Metric,
+) telegraf.TrackingID {
+	group, id := metric.WithGroupTracking(group,
+		func(info telegraf.DeliveryInfo) {
+			a.delivered <- info
+		})
+	for _, m := range group {
+		a.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+		m.Accept()
+	}
+	return id
+}
+
+func (a *TrackingAccumulator) Delivered() <-chan telegraf.DeliveryInfo {
+	return a.delivered
+}
+
 func (a *Accumulator) SetPrecision(precision, interval time.Duration) {
 	return
 }
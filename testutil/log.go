@@ -0,0 +1,51 @@
+package testutil
+
+import (
+	"fmt"
+	"log"
+)
+
+// Logger is a telegraf.Logger writing to the standard logger, for the tests
+// of the plugins.
+type Logger struct {
+	Name string
+}
+
+func (l Logger) print(prefix, msg string) {
+	if l.Name != "" {
+		prefix += " [" + l.Name + "]"
+	}
+	log.Print(prefix + " " + msg)
+}
+
+func (l Logger) Errorf(format string, args ...interface{}) {
+	l.print("E!", fmt.Sprintf(format, args...))
+}
+
+func (l Logger) Error(args ...interface{}) {
+	l.print("E!", fmt.Sprint(args...))
+}
+
+func (l Logger) Warnf(format string, args ...interface{}) {
+	l.print("W!", fmt.Sprintf(format, args...))
+}
+
+func (l Logger) Warn(args ...interface{}) {
+	l.print("W!", fmt.Sprint(args...))
+}
+
+func (l Logger) Infof(format string, args ...interface{}) {
+	l.print("I!", fmt.Sprintf(format, args...))
+}
+
+func (l Logger) Info(args ...interface{}) {
+	l.print("I!", fmt.Sprint(args...))
+}
+
+func (l Logger) Debugf(format string, args ...interface{}) {
+	l.print("D!", fmt.Sprintf(format, args...))
+}
+
+func (l Logger) Debug(args ...interface{}) {
+	l.print("D!", fmt.Sprint(args...))
+}